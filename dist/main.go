@@ -2,10 +2,13 @@ package main
 
 import (
 	_ "embed"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"strings"
 
+	spanreportexporter "github.com/kmuto/span-report-collector/spanreportexporter"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/confmap"
 	"go.opentelemetry.io/collector/confmap/provider/envprovider"
@@ -27,6 +30,42 @@ func getEnv(key, fallback string) string {
 }
 
 func main() {
+	// 0. Subcommand: show the TUI of a collector running elsewhere
+	if len(os.Args) > 1 && os.Args[1] == "attach" {
+		fs := flag.NewFlagSet("attach", flag.ExitOnError)
+		endpoint := fs.String("endpoint", getEnv("SPAN_REPORT_CONTROL_ENDPOINT", ""), "control endpoint of the running collector")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: span-report-collector attach [-endpoint unix:///path/to.sock | tcp://host:port]\n")
+			fs.PrintDefaults()
+		}
+		fs.Parse(os.Args[2:])
+		if *endpoint == "" || fs.NArg() > 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err := spanreportexporter.Attach(*endpoint); err != nil {
+			log.Fatalf("failed to attach: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+		endpoint := fs.String("endpoint", getEnv("SPAN_REPORT_CONTROL_ENDPOINT", ""), "control endpoint (Unix socket) of the running collector")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: span-report-collector ctl [-endpoint unix:///path/to.sock] <command> [args...]\n%s\n", spanreportexporter.ControlUsage)
+			fs.PrintDefaults()
+		}
+		fs.Parse(os.Args[2:])
+		if *endpoint == "" || fs.NArg() == 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err := spanreportexporter.Control(*endpoint, fs.Args(), os.Stdout); err != nil {
+			log.Fatalf("ctl %s: %v", fs.Arg(0), err)
+		}
+		return
+	}
+
 	// 1. Check command-line arguments
 	useDefault := true
 	for _, arg := range os.Args {
@@ -84,6 +123,7 @@ func main() {
 			"{{REPORT_PATH}}", getEnv("SPAN_REPORT_PATH", "./span_report.txt"),
 			"{{REPORT_INTERVAL}}", getEnv("SPAN_REPORT_INTERVAL", "1h"),
 			"{{VERBOSE_LOGGING}}", getEnv("SPAN_REPORT_VERBOSE", "false"),
+			"{{CONTROL_ENDPOINT}}", getEnv("SPAN_REPORT_CONTROL_ENDPOINT", ""),
 			"{{LOG_LEVEL}}", loglevel,
 		)
 
@@ -0,0 +1,76 @@
+package spanreportexporter
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// resetGroup sets the counters of one period of a group to 0. Without a period,
+// the group is removed altogether.
+func (e *spanReportExporter) resetGroup(k groupingKey, p *period) error {
+	e.reportMu.Lock()
+	defer e.reportMu.Unlock()
+
+	val, ok := e.statsMap.Load(k)
+	if !ok {
+		return fmt.Errorf("no such group: %s", k.label())
+	}
+	if p == nil {
+		e.statsMap.Delete(k)
+		e.forgetGroup(k)
+		e.forgetBaselines(k)
+	} else {
+		val.(*spanStats).reset(*p)
+	}
+	e.logger.Info("Group reset by admin command",
+		zap.String("tenant", k.tenant),
+		zap.String("namespace", k.namespace),
+		zap.String("service", k.service),
+		zap.String("environment", k.env),
+	)
+	return nil
+}
+
+// mergeService adds the counters of every environment of service "from" to the
+// same environment of service "to", and removes "from". It returns the number
+// of groups merged.
+//
+// Consumers are not blocked: a batch that got a group of "from" just before it
+// was removed may still add to it. A second pass moves those counts too, but
+// counts added after it are lost.
+func (e *spanReportExporter) mergeService(from, to string) (int, error) {
+	if from == to {
+		return 0, fmt.Errorf("cannot merge %s into itself", from)
+	}
+
+	e.reportMu.Lock()
+	defer e.reportMu.Unlock()
+
+	var moved []struct{ src, dst *spanStats }
+	for _, entry := range e.getSortedEntries() {
+		if entry.key.service != from {
+			continue
+		}
+		dstKey := entry.key
+		dstKey.service = to
+		dst, loaded := e.statsMap.LoadOrStore(dstKey, &spanStats{})
+		e.statsMap.Delete(entry.key)
+		if loaded {
+			e.forgetGroup(entry.key)
+		}
+		e.forgetBaselines(entry.key)
+		m := struct{ src, dst *spanStats }{entry.stats, dst.(*spanStats)}
+		m.src.moveTo(m.dst)
+		moved = append(moved, m)
+	}
+	for _, m := range moved {
+		m.src.moveTo(m.dst)
+	}
+	n := len(moved)
+	if n == 0 {
+		return 0, fmt.Errorf("no such service: %s", from)
+	}
+	e.logger.Info("Service merged by admin command", zap.String("from", from), zap.String("to", to), zap.Int("groups", n))
+	return n, nil
+}
@@ -0,0 +1,141 @@
+package spanreportexporter
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// AnomalyConfig controls the detection of unusual hourly span counts.
+type AnomalyConfig struct {
+	// Enabled turns on the detection.
+	Enabled bool `mapstructure:"enabled"`
+	// Threshold is the number of standard deviations a count may deviate from the baseline.
+	Threshold float64 `mapstructure:"threshold"`
+	// Alpha is the weight of the newest count in the moving average (0 < alpha <= 1).
+	Alpha float64 `mapstructure:"alpha"`
+	// MinSamples is the number of weeks observed before an hour of the week is evaluated.
+	MinSamples int `mapstructure:"min_samples"`
+}
+
+func (c AnomalyConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Threshold <= 0 {
+		return fmt.Errorf("anomaly.threshold must be positive")
+	}
+	if c.Alpha <= 0 || c.Alpha > 1 {
+		return fmt.Errorf("anomaly.alpha must be in (0, 1]")
+	}
+	if c.MinSamples < 1 {
+		return fmt.Errorf("anomaly.min_samples must be at least 1")
+	}
+	return nil
+}
+
+// Anomaly is an hourly count that deviates from the baseline of its group.
+type Anomaly struct {
+	// Time is the start of the hour
+	Time      time.Time `json:"time"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Service   string    `json:"service"`
+	Env       string    `json:"env"`
+	Count     uint64    `json:"count"`
+	Expected  float64   `json:"expected"`
+	StdDev    float64   `json:"stddev"`
+	// Score is the deviation in standard deviations; negative for a drop
+	Score float64 `json:"score"`
+}
+
+// hoursPerWeek is the number of baselines of a group: traffic usually depends
+// on the hour of the day and on the day of the week.
+const hoursPerWeek = 7 * 24
+
+// ewma is an exponentially weighted moving average and variance.
+type ewma struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+func (b *ewma) update(x, alpha float64) {
+	if b.samples == 0 {
+		b.mean = x
+	} else {
+		d := x - b.mean
+		b.mean += alpha * d
+		b.variance = (1 - alpha) * (b.variance + alpha*d*d)
+	}
+	b.samples++
+}
+
+// stddev returns the standard deviation of the baseline, with a floor of the
+// Poisson noise sqrt(mean), so that a group with perfectly steady traffic is
+// not flagged for a handful of extra spans.
+func (b *ewma) stddev() float64 {
+	return math.Max(math.Sqrt(b.variance), math.Max(math.Sqrt(b.mean), 1))
+}
+
+// anomalyDetector keeps an EWMA baseline per group and hour of the week, and
+// the anomaly of the last evaluated hour of each group.
+type anomalyDetector struct {
+	mu        sync.Mutex
+	cfg       AnomalyConfig
+	baselines map[groupingKey]*[hoursPerWeek]ewma
+	current   map[groupingKey]*Anomaly
+}
+
+func newAnomalyDetector(cfg AnomalyConfig) *anomalyDetector {
+	return &anomalyDetector{
+		cfg:       cfg,
+		baselines: make(map[groupingKey]*[hoursPerWeek]ewma),
+		current:   make(map[groupingKey]*Anomaly),
+	}
+}
+
+// observe evaluates the count of the finished hour that started at hour, then
+// adds it to the baseline. It returns the anomaly, or nil if the count is normal.
+func (d *anomalyDetector) observe(k groupingKey, hour time.Time, count uint64) *Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	baselines := d.baselines[k]
+	if baselines == nil {
+		baselines = &[hoursPerWeek]ewma{}
+		d.baselines[k] = baselines
+	}
+	b := &baselines[int(hour.Weekday())*24+hour.Hour()]
+
+	var anomaly *Anomaly
+	x := float64(count)
+	if b.samples >= d.cfg.MinSamples {
+		sd := b.stddev()
+		score := (x - b.mean) / sd
+		if math.Abs(score) > d.cfg.Threshold {
+			anomaly = &Anomaly{
+				Time:      hour,
+				Tenant:    k.tenant,
+				Namespace: k.namespace,
+				Service:   k.service,
+				Env:       k.env,
+				Count:     count,
+				Expected:  b.mean,
+				StdDev:    sd,
+				Score:     score,
+			}
+		}
+	}
+	b.update(x, d.cfg.Alpha)
+	d.current[k] = anomaly
+	return anomaly
+}
+
+// anomaly returns the anomaly of the last evaluated hour of a group.
+func (d *anomalyDetector) anomaly(k groupingKey) *Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.current[k]
+}
@@ -0,0 +1,71 @@
+package spanreportexporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	d := newAnomalyDetector(AnomalyConfig{Enabled: true, Threshold: 3, Alpha: 0.3, MinSamples: 3})
+	key := groupingKey{tenant: "acme", namespace: "shop", service: "svc", env: "prod"}
+	monday := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	// Three weeks of the usual volume on Monday 10:00; other hours have their own baseline
+	for week, count := range []uint64{1000, 1040, 980} {
+		assert.Nil(t, d.observe(key, monday.AddDate(0, 0, 7*week), count))
+		assert.Nil(t, d.observe(key, monday.AddDate(0, 0, 7*week).Add(time.Hour), 10))
+	}
+	assert.Nil(t, d.observe(key, monday.AddDate(0, 0, 21), 1020), "a usual count is not flagged")
+
+	// A tenfold spike is flagged, and kept as the current anomaly of the group
+	a := d.observe(key, monday.AddDate(0, 0, 28), 10000)
+	require.NotNil(t, a)
+	assert.Equal(t, "svc", a.Service)
+	assert.Equal(t, "acme", a.Tenant)
+	assert.Equal(t, "shop", a.Namespace)
+	assert.Equal(t, uint64(10000), a.Count)
+	assert.InDelta(t, 1010, a.Expected, 20)
+	assert.Greater(t, a.Score, 3.0)
+	assert.Equal(t, a, d.anomaly(key))
+
+	// A drop to zero in another hour is flagged with a negative score
+	a = d.observe(key, monday.AddDate(0, 0, 21).Add(time.Hour), 0)
+	require.NotNil(t, a)
+	assert.Less(t, a.Score, -3.0)
+}
+
+func TestNotifyAnomaly(t *testing.T) {
+	var events []Event
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&ev))
+		events = append(events, ev)
+	}))
+	defer webhook.Close()
+
+	sinks, err := openSinks([]SinkConfig{
+		{Name: "reports", Type: sinkTypeWebhook, URL: webhook.URL},
+		{Name: "alerts", Type: sinkTypeWebhook, URL: webhook.URL, Events: true},
+	})
+	require.NoError(t, err)
+	core, logs := observer.New(zap.WarnLevel)
+	exp := &spanReportExporter{sinks: sinks, logger: zap.New(core)}
+
+	exp.notifyAnomaly(Anomaly{Service: "svc", Env: "prod", Count: 10000, Expected: 1000, StdDev: 40, Score: 225})
+
+	// Only the sink with events enabled is notified
+	require.Len(t, events, 1)
+	assert.Equal(t, EventTypeAnomaly, events[0].Type)
+	assert.Equal(t, uint64(10000), events[0].Anomaly.Count)
+	entries := logs.FilterMessage("Span volume anomaly").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "svc", entries[0].ContextMap()["service"])
+}
@@ -0,0 +1,132 @@
+package spanreportexporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Change compares the Total of a period with the Total of an earlier equivalent period.
+type Change struct {
+	Previous uint64 `json:"previous"`
+	Delta    int64  `json:"delta"`
+	// Percent is nil when Previous is 0
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// PeriodChanges holds the period-over-period changes of a group. A field is
+// nil when the earlier period has not been observed (e.g. after a restart).
+type PeriodChanges struct {
+	LastHour      *Change `json:"last_hour,omitempty"`
+	HourYesterday *Change `json:"hour_yesterday,omitempty"`
+	DayLastWeek   *Change `json:"day_last_week,omitempty"`
+	LastMonth     *Change `json:"last_month,omitempty"`
+}
+
+func newChange(current, previous uint64) *Change {
+	c := &Change{Previous: previous, Delta: int64(current) - int64(previous)}
+	if previous > 0 {
+		pct := float64(c.Delta) * 100 / float64(previous)
+		c.Percent = &pct
+	}
+	return c
+}
+
+// formatChange formats a change as e.g. "+120 (+8.0%)", or "n/a" without an earlier period.
+func formatChange(c *Change) string {
+	if c == nil {
+		return "n/a"
+	}
+	if c.Percent == nil {
+		return fmt.Sprintf("%+d", c.Delta)
+	}
+	return fmt.Sprintf("%+d (%+.1f%%)", c.Delta, *c.Percent)
+}
+
+// periodStart returns the start of the period p that contains t.
+func periodStart(p period, t time.Time) time.Time {
+	switch p {
+	case periodDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case periodMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	}
+}
+
+// priorRetention is how far back finished periods are kept: far enough for
+// the oldest comparison of each period (a day, a week and a month).
+var priorRetention = map[period]func(time.Time) time.Time{
+	periodHourly:  func(t time.Time) time.Time { return t.AddDate(0, 0, -1) },
+	periodDaily:   func(t time.Time) time.Time { return t.AddDate(0, 0, -7) },
+	periodMonthly: func(t time.Time) time.Time { return t.AddDate(0, -1, 0) },
+}
+
+type priorKey struct {
+	period period
+	start  int64 // Unix time of the period start
+}
+
+// priorStore remembers the totals of finished periods per group, so that
+// reports can show the change versus earlier periods.
+type priorStore struct {
+	mu     sync.Mutex
+	totals map[groupingKey]map[priorKey]PeriodCounts
+}
+
+func newPriorStore() *priorStore {
+	return &priorStore{totals: make(map[groupingKey]map[priorKey]PeriodCounts)}
+}
+
+// record stores the counts of the period p of group k that started at start,
+// and forgets the periods of p that are too old to be compared with.
+func (s *priorStore) record(k groupingKey, p period, start time.Time, c PeriodCounts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totals := s.totals[k]
+	if totals == nil {
+		totals = make(map[priorKey]PeriodCounts)
+		s.totals[k] = totals
+	}
+	totals[priorKey{p, start.Unix()}] = c
+
+	cutoff := priorRetention[p](start).Unix()
+	for pk := range totals {
+		if pk.period == p && pk.start < cutoff {
+			delete(totals, pk)
+		}
+	}
+}
+
+func (s *priorStore) lookup(k groupingKey, p period, start time.Time) (PeriodCounts, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.totals[k][priorKey{p, start.Unix()}]
+	return c, ok
+}
+
+// changes compares a report row with the remembered periods. starts holds the
+// start of the period each counter of the row belongs to. A day or month is
+// only compared once it has finished, since its partial count would look like
+// a drop against a complete earlier period.
+func (s *priorStore) changes(k groupingKey, row GroupCounts, starts map[period]time.Time, finished map[period]bool) PeriodChanges {
+	compare := func(p period, current uint64, start time.Time) *Change {
+		if p != periodHourly && !finished[p] {
+			return nil
+		}
+		prev, ok := s.lookup(k, p, start)
+		if !ok {
+			return nil
+		}
+		return newChange(current, prev.Total)
+	}
+	hour, day, month := starts[periodHourly], starts[periodDaily], starts[periodMonthly]
+	return PeriodChanges{
+		LastHour:      compare(periodHourly, row.Hourly.Total, hour.Add(-time.Hour)),
+		HourYesterday: compare(periodHourly, row.Hourly.Total, hour.AddDate(0, 0, -1)),
+		DayLastWeek:   compare(periodDaily, row.Daily.Total, day.AddDate(0, 0, -7)),
+		LastMonth:     compare(periodMonthly, row.Monthly.Total, month.AddDate(0, -1, 0)),
+	}
+}
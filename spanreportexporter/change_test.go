@@ -0,0 +1,85 @@
+package spanreportexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectReport_PeriodOverPeriod(t *testing.T) {
+	exp := &spanReportExporter{prior: newPriorStore()}
+	key := groupingKey{service: "svc", env: "env"}
+	stats := &spanStats{}
+	exp.statsMap.Store(key, stats)
+
+	// report counts n spans during the hour before now, like the hourly ticker
+	report := func(now time.Time, n uint64) GroupCounts {
+		stats.hourly.Add(n)
+		stats.daily.Add(n)
+		stats.monthly.Add(n)
+		exp.lastExportTime = now.Add(-time.Hour)
+		r := exp.collectReport(now)
+		require.Len(t, r.Groups, 1)
+		return r.Groups[0]
+	}
+
+	// The report at a boundary contains the period that has just ended
+	start := time.Date(2025, 12, 10, 10, 0, 0, 0, time.UTC)
+	row := report(start, 100)
+	assert.Equal(t, uint64(100), row.Hourly.Total)
+	assert.Equal(t, uint64(0), stats.hourly.Load())
+	assert.Nil(t, row.Changes.LastHour)
+
+	row = report(start.Add(time.Hour), 120)
+	require.NotNil(t, row.Changes.LastHour)
+	assert.Equal(t, int64(20), row.Changes.LastHour.Delta)
+	assert.InDelta(t, 20.0, *row.Changes.LastHour.Percent, 0.001)
+	assert.Equal(t, "+20 (+20.0%)", formatChange(row.Changes.LastHour))
+	assert.Nil(t, row.Changes.HourYesterday)
+
+	// Fill up the day, then the same hour of the next day is compared with yesterday
+	for h := 2; h < 24; h++ {
+		report(start.Add(time.Duration(h)*time.Hour), 10)
+	}
+	row = report(start.Add(24*time.Hour), 50)
+	require.NotNil(t, row.Changes.HourYesterday)
+	assert.Equal(t, int64(-50), row.Changes.HourYesterday.Delta)
+	assert.Equal(t, "-50 (-50.0%)", formatChange(row.Changes.HourYesterday))
+	assert.Nil(t, row.Changes.DayLastWeek)
+	assert.Equal(t, "n/a", formatChange(row.Changes.LastMonth))
+}
+
+func TestPriorStore_ComparesFinishedDaysOnly(t *testing.T) {
+	s := newPriorStore()
+	key := groupingKey{service: "svc", env: "env"}
+	day := time.Date(2025, 12, 18, 0, 0, 0, 0, time.UTC)
+	s.record(key, periodDaily, day.AddDate(0, 0, -7), PeriodCounts{Total: 24000})
+	starts := map[period]time.Time{periodHourly: day, periodDaily: day, periodMonthly: day.AddDate(0, 0, -17)}
+
+	// With steady traffic, the first hour of the day is not a drop
+	row := GroupCounts{Daily: PeriodCounts{Total: 1000}}
+	assert.Nil(t, s.changes(key, row, starts, map[period]bool{periodHourly: true}).DayLastWeek)
+
+	// The final value of the day is compared once it has finished
+	row = GroupCounts{Daily: PeriodCounts{Total: 24600}}
+	changes := s.changes(key, row, starts, map[period]bool{periodHourly: true, periodDaily: true})
+	assert.Equal(t, "+600 (+2.5%)", formatChange(changes.DayLastWeek))
+}
+
+func TestPriorStore_Retention(t *testing.T) {
+	s := newPriorStore()
+	key := groupingKey{service: "svc", env: "env"}
+	day := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		s.record(key, periodDaily, day.AddDate(0, 0, i), PeriodCounts{Total: uint64(i)})
+	}
+
+	// Only the days needed for the week-over-week comparison are kept
+	assert.Len(t, s.totals[key], 8)
+	_, ok := s.lookup(key, periodDaily, day.AddDate(0, 0, 22))
+	assert.True(t, ok)
+	_, ok = s.lookup(key, periodDaily, day.AddDate(0, 0, 21))
+	assert.False(t, ok)
+}
@@ -0,0 +1,251 @@
+package spanreportexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// parseControlEndpoint splits "unix:///path/to.sock" or "tcp://host:port" into
+// network and address. A value without a scheme is a Unix socket path if it
+// contains a path separator, and a TCP address otherwise.
+func parseControlEndpoint(endpoint string) (network, address string) {
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"):
+		return "unix", strings.TrimPrefix(endpoint, "unix://")
+	case strings.HasPrefix(endpoint, "tcp://"):
+		return "tcp", strings.TrimPrefix(endpoint, "tcp://")
+	case strings.ContainsAny(endpoint, `/\`):
+		return "unix", endpoint
+	default:
+		return "tcp", endpoint
+	}
+}
+
+// listenControl opens the control endpoint. A socket file left behind by a
+// collector that did not shut down cleanly is removed first, while any other
+// file is left alone, and a new socket is only accessible by the user running
+// the collector, see listenUnix.
+func listenControl(endpoint string) (net.Listener, error) {
+	network, address := parseControlEndpoint(endpoint)
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	if info, err := os.Stat(address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", address)
+		}
+		if conn, err := net.DialTimeout("unix", address, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another collector is listening on %s", address)
+		}
+		os.Remove(address)
+	}
+	return listenUnix(address)
+}
+
+// newControlHandler returns the handler served on the control endpoint.
+// The admin operations are only available on a Unix socket, whose access is
+// restricted by file permissions; a TCP endpoint is read-only.
+//
+//	GET  /api/snapshot                              current counters of all groups
+//	POST /admin/flush                               write a report now
+//	POST /admin/reset?service=&env=[&period=]       reset a group (and namespace=, tenant= if enabled)
+//	POST /admin/merge?from=&to=                     merge a service into another
+func (e *spanReportExporter) newControlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/snapshot", e.handleSnapshot)
+	if network, _ := parseControlEndpoint(e.controlEndpoint); network == "unix" {
+		mux.HandleFunc("POST /admin/flush", e.handleFlush)
+		mux.HandleFunc("POST /admin/reset", e.handleReset)
+		mux.HandleFunc("POST /admin/merge", e.handleMerge)
+	} else {
+		mux.HandleFunc("POST /admin/", func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "admin commands are only available on a Unix socket control endpoint", http.StatusForbidden)
+		})
+	}
+	return mux
+}
+
+func (e *spanReportExporter) handleFlush(w http.ResponseWriter, _ *http.Request) {
+	e.flushReport()
+	fmt.Fprintln(w, "report written")
+}
+
+func (e *spanReportExporter) handleReset(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	k := groupingKey{tenant: q.Get("tenant"), namespace: q.Get("namespace"), service: q.Get("service"), env: q.Get("env")}
+	// ctl splits <tenant>:<namespace>/<service>, which are plain service names when disabled
+	if k.namespace != "" && !e.hierarchy {
+		k.service, k.namespace = k.namespace+"/"+k.service, ""
+	}
+	if k.tenant != "" && e.tenantHeader == "" {
+		k.service, k.tenant = k.tenant+":"+k.service, ""
+	}
+	var p *period
+	if v := q.Get("period"); v != "" {
+		parsed, err := parsePeriod(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p = &parsed
+	}
+	if err := e.resetGroup(k, p); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	label := k.label()
+	if p == nil {
+		fmt.Fprintf(w, "removed %s\n", label)
+	} else {
+		fmt.Fprintf(w, "reset %s counters of %s\n", q.Get("period"), label)
+	}
+}
+
+func (e *spanReportExporter) handleMerge(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	n, err := e.mergeService(from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Fprintf(w, "merged %d group(s) of %s into %s\n", n, from, to)
+}
+
+// controlClient talks to the control endpoint of a running collector.
+type controlClient struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newControlClient(endpoint string) *controlClient {
+	network, address := parseControlEndpoint(endpoint)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+	return &controlClient{
+		endpoint: endpoint,
+		client:   &http.Client{Transport: transport, Timeout: 5 * time.Second},
+	}
+}
+
+// The host part is ignored by the dialer, but required to build a valid URL.
+const controlBaseURL = "http://control"
+
+func (c *controlClient) snapshot() (snapshotResponse, error) {
+	var snap snapshotResponse
+	res, err := c.client.Get(controlBaseURL + "/api/snapshot")
+	if err != nil {
+		return snap, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return snap, fmt.Errorf("unexpected response from %s: %s", c.endpoint, res.Status)
+	}
+	err = json.NewDecoder(res.Body).Decode(&snap)
+	return snap, err
+}
+
+// post sends an admin command and returns the response text.
+func (c *controlClient) post(path string, params url.Values) (string, error) {
+	res, err := c.client.Post(controlBaseURL+path+"?"+params.Encode(), "text/plain", nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// ControlUsage describes the commands accepted by Control.
+const ControlUsage = `commands:
+  flush                          write a report now
+  reset <service> <env> [period] reset the counters of a period (hourly, daily or monthly),
+                                 or remove the group if no period is given; <service> is
+                                 [<tenant>:][<namespace>/]<service> with tenants and hierarchy
+  merge <from> <to>              add the counters of service <from> to service <to>
+                                 (per environment) and remove <from>
+  snapshot                       print the current counters as JSON`
+
+// Control runs an admin command against the collector listening on the given
+// control endpoint and writes the result to out.
+func Control(endpoint string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given\n%s", ControlUsage)
+	}
+	c := newControlClient(endpoint)
+
+	var msg string
+	var err error
+	switch cmd := args[0]; {
+	case cmd == "flush" && len(args) == 1:
+		msg, err = c.post("/admin/flush", nil)
+	case cmd == "reset" && (len(args) == 3 || len(args) == 4):
+		params := url.Values{"service": {args[1]}, "env": {args[2]}}
+		service := args[1]
+		if tenant, rest, ok := strings.Cut(service, ":"); ok {
+			params.Set("tenant", tenant)
+			service = rest
+		}
+		if namespace, rest, ok := strings.Cut(service, "/"); ok {
+			params.Set("namespace", namespace)
+			service = rest
+		}
+		params.Set("service", service)
+		if len(args) == 4 {
+			if _, err := parsePeriod(args[3]); err != nil {
+				return err
+			}
+			params.Set("period", args[3])
+		}
+		msg, err = c.post("/admin/reset", params)
+	case cmd == "merge" && len(args) == 3:
+		msg, err = c.post("/admin/merge", url.Values{"from": {args[1]}, "to": {args[2]}})
+	case cmd == "snapshot" && len(args) == 1:
+		var snap snapshotResponse
+		if snap, err = c.snapshot(); err == nil {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(snap)
+		}
+	default:
+		return fmt.Errorf("invalid command: %s\n%s", strings.Join(args, " "), ControlUsage)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, msg)
+	return err
+}
+
+// Attach shows the TUI of the collector listening on the given control endpoint.
+// The counters are fetched every second, so the collector itself can run without a terminal.
+func Attach(endpoint string) error {
+	c := newControlClient(endpoint)
+	if _, err := c.snapshot(); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	p := tea.NewProgram(newTUIModel(c.snapshot), tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
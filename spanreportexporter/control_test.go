@@ -0,0 +1,142 @@
+package spanreportexporter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseControlEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		network  string
+		address  string
+	}{
+		{"unix:///run/span-report-collector/control.sock", "unix", "/run/span-report-collector/control.sock"},
+		{"tcp://localhost:7070", "tcp", "localhost:7070"},
+		{"/tmp/control.sock", "unix", "/tmp/control.sock"},
+		{"localhost:7070", "tcp", "localhost:7070"},
+	}
+	for _, tt := range tests {
+		network, address := parseControlEndpoint(tt.endpoint)
+		assert.Equal(t, tt.network, network, tt.endpoint)
+		assert.Equal(t, tt.address, address, tt.endpoint)
+	}
+}
+
+func TestControl_Snapshot(t *testing.T) {
+	exp := newAPITestExporter()
+	exp.stopCh = make(chan struct{})
+	exp.reportInterval = time.Hour
+	exp.controlEndpoint = "unix://" + filepath.Join(t.TempDir(), "control.sock")
+	require.NoError(t, exp.Start(context.Background(), nil))
+	defer exp.Shutdown(context.Background())
+
+	// A second collector must not steal the socket
+	_, err := listenControl(exp.controlEndpoint)
+	assert.Error(t, err)
+
+	snap, err := newControlClient(exp.controlEndpoint).snapshot()
+	require.NoError(t, err)
+	require.Len(t, snap.Groups, 3)
+	assert.Equal(t, "auth-svc", snap.Groups[0].Service)
+	assert.Equal(t, uint64(10), snap.Groups[0].Hourly.Total)
+	assert.Equal(t, exp.startTime.Unix(), snap.StartTime.Unix())
+}
+
+func TestListenControl_KeepsOtherFiles(t *testing.T) {
+	// A control_endpoint pointing at a regular file by mistake
+	path := filepath.Join(t.TempDir(), "span_report.txt")
+	require.NoError(t, os.WriteFile(path, []byte("report"), 0644))
+
+	_, err := listenControl("unix://" + path)
+	assert.ErrorContains(t, err, "not a socket")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "report", string(data))
+}
+
+func TestControl_AdminCommands(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "span_control_test.txt")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	exp := newAPITestExporter()
+	exp.sinks, err = openSinks([]SinkConfig{{Type: sinkTypeFile, Path: tmpFile.Name()}})
+	require.NoError(t, err)
+	exp.controlEndpoint = "unix://" + filepath.Join(t.TempDir(), "control.sock")
+	ln, err := listenControl(exp.controlEndpoint)
+	require.NoError(t, err)
+	srv := exp.serveHTTP(ln, exp.newControlHandler())
+	defer srv.Close()
+
+	// The socket is only accessible by its owner
+	_, address := parseControlEndpoint(exp.controlEndpoint)
+	info, err := os.Stat(address)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := Control(exp.controlEndpoint, args, &out)
+		return out.String(), err
+	}
+
+	// flush
+	_, err = run("flush")
+	require.NoError(t, err)
+	content, err := os.ReadFile(tmpFile.Name())
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "service:order-api, env:prod")
+
+	// reset of one period, then of the whole group
+	_, err = run("reset", "order-api", "prod", "hourly")
+	require.NoError(t, err)
+	val, _ := exp.statsMap.Load(groupingKey{service: "order-api", env: "prod"})
+	assert.Equal(t, uint64(0), val.(*spanStats).hourly.Load())
+	_, err = run("reset", "order-api", "dev")
+	require.NoError(t, err)
+	_, ok := exp.statsMap.Load(groupingKey{service: "order-api", env: "dev"})
+	assert.False(t, ok)
+	_, err = run("reset", "no-such", "prod")
+	assert.ErrorContains(t, err, "no such group")
+	_, err = run("reset", "order-api", "prod", "weekly")
+	assert.Error(t, err)
+
+	// merge: auth-svc/prod (10) is folded into order-api/prod (0 after the reset)
+	out, err := run("merge", "auth-svc", "order-api")
+	require.NoError(t, err)
+	assert.Contains(t, out, "merged 1 group(s)")
+	val, _ = exp.statsMap.Load(groupingKey{service: "order-api", env: "prod"})
+	assert.Equal(t, uint64(10), val.(*spanStats).hourly.Load())
+	_, ok = exp.statsMap.Load(groupingKey{service: "auth-svc", env: "prod"})
+	assert.False(t, ok)
+
+	// snapshot
+	out, err = run("snapshot")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"service": "order-api"`)
+
+	_, err = run("restart")
+	assert.ErrorContains(t, err, "invalid command")
+}
+
+func TestControl_TCPIsReadOnly(t *testing.T) {
+	exp := newAPITestExporter()
+	exp.controlEndpoint = "tcp://localhost:0"
+	srv := httptest.NewServer(exp.newControlHandler())
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/admin/flush", "text/plain", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package spanreportexporter
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serializes the umask changes of listenUnix.
+var umaskMu sync.Mutex
+
+// listenUnix creates the socket with permission 0600 from the start: the umask
+// is process-wide, so it is only narrowed around net.Listen instead of
+// changing the mode afterwards, which would let other users connect meanwhile.
+func listenUnix(address string) (net.Listener, error) {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", address)
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package spanreportexporter
+
+import "net"
+
+// listenUnix creates the socket, whose access is controlled by the ACL of
+// its directory on Windows.
+func listenUnix(address string) (net.Listener, error) {
+	return net.Listen("unix", address)
+}
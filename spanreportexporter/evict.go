@@ -0,0 +1,106 @@
+package spanreportexporter
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// touch records that the group received data, see idleSince.
+func (s *spanStats) touch(now time.Time) {
+	s.lastSeen.Store(now.UnixNano())
+}
+
+// seen sets lastSeen to t unless the group received data later.
+func (s *spanStats) seen(t int64) {
+	for {
+		last := s.lastSeen.Load()
+		if last >= t || s.lastSeen.CompareAndSwap(last, t) {
+			return
+		}
+	}
+}
+
+// idleSince reports whether the group received nothing since t.
+func (s *spanStats) idleSince(t time.Time) bool {
+	return s.lastSeen.Load() < t.UnixNano()
+}
+
+// isZero reports whether nothing was counted in the period.
+func (c PeriodCounts) isZero() bool {
+	return c.Total == 0 && c.Logs == 0 && c.DataPoints == 0
+}
+
+// isZero reports whether nothing was counted in any period. The counters of a
+// period that has just finished are in the group until the report is written.
+func (g GroupCounts) isZero() bool {
+	return g.Hourly.isZero() && g.Daily.isZero() && g.Monthly.isZero()
+}
+
+// evictGroup removes group k, with its baselines, if it received nothing since
+// t. It reports whether the group was removed.
+func (e *spanReportExporter) evictGroup(k groupingKey, s *spanStats, t time.Time, reason string) bool {
+	if !s.idleSince(t) || !e.statsMap.CompareAndDelete(k, s) {
+		return false
+	}
+	if !s.idleSince(t) {
+		// Data arrived for the group while it was removed: keep counting it
+		if cur, loaded := e.statsMap.LoadOrStore(k, s); loaded {
+			e.forgetGroup(k)
+			s.moveTo(cur.(*spanStats))
+		}
+		return false
+	}
+	e.forgetGroup(k)
+	e.forgetBaselines(k)
+	if e.verbose {
+		e.logger.Info("Evicted idle group",
+			zap.String("service", k.service),
+			zap.String("environment", k.env),
+			zap.String("reason", reason),
+		)
+	}
+	return true
+}
+
+// forgetBaselines removes the earlier periods and the anomaly baselines of a
+// removed group, so that a group created again with the same key starts afresh.
+func (e *spanReportExporter) forgetBaselines(k groupingKey) {
+	if e.prior != nil {
+		e.prior.forget(k)
+	}
+	if e.anomalies != nil {
+		e.anomalies.forget(k)
+	}
+}
+
+// forget removes the periods remembered for group k.
+func (s *priorStore) forget(k groupingKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.totals, k)
+}
+
+// forget removes the baselines of group k.
+func (d *anomalyDetector) forget(k groupingKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.baselines, k)
+	delete(d.current, k)
+}
+
+// withoutZeroGroups returns the groups that counted something in the hour of the
+// report. Groups with the final value of a day or month that has just finished
+// are kept, and so are those flagged as anomalous since a drop to zero is worth
+// seeing.
+func withoutZeroGroups(groups []GroupCounts, finished map[period]bool) []GroupCounts {
+	var kept []GroupCounts
+	for _, g := range groups {
+		if !g.Hourly.isZero() || g.Anomaly != nil ||
+			(finished[periodDaily] && !g.Daily.isZero()) ||
+			(finished[periodMonthly] && !g.Monthly.isZero()) {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
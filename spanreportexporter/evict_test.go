@@ -0,0 +1,169 @@
+package spanreportexporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestCollectReport_EvictsGroupsIdleForAWholeMonth(t *testing.T) {
+	exp := &spanReportExporter{logger: componenttest.NewNopTelemetrySettings().Logger}
+	active := groupingKey{service: "order-api", env: "prod"}
+	retired := groupingKey{service: "legacy-api", env: "prod"}
+	exp.statsFor(active).monthly.Store(10)
+	exp.statsFor(retired).monthly.Store(5000)
+	exp.statsFor(retired).touch(time.Date(2025, 12, 31, 22, 0, 0, 0, time.UTC))
+
+	// The month ends: both groups are reported with their final values
+	exp.lastExportTime = time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC)
+	report := exp.collectReport(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, report.Groups, 2)
+
+	// A quiet first hour of the month does not remove the group
+	exp.lastExportTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	report = exp.collectReport(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC))
+	require.Len(t, report.Groups, 2)
+	_, ok := exp.statsMap.Load(retired)
+	assert.True(t, ok)
+
+	// Only the active group receives data in January: the retired one is
+	// removed when the month ends
+	exp.statsFor(active).monthly.Add(1)
+	exp.lastExportTime = time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	report = exp.collectReport(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, "order-api", report.Groups[0].Service)
+	_, ok = exp.statsMap.Load(retired)
+	assert.False(t, ok)
+}
+
+func TestCollectReport_IdleTTLAndSuppressZero(t *testing.T) {
+	exp := &spanReportExporter{
+		logger:       componenttest.NewNopTelemetrySettings().Logger,
+		idleTTL:      time.Hour,
+		suppressZero: true,
+	}
+	now := time.Now()
+	busy := exp.statsFor(groupingKey{service: "busy", env: "prod"})
+	busy.hourly.Store(5)
+	busy.monthly.Store(5)
+	quiet := exp.statsFor(groupingKey{service: "quiet", env: "prod"})
+	quiet.monthly.Store(7)
+	exp.statsFor(groupingKey{service: "idle", env: "prod"})
+	stale := exp.statsFor(groupingKey{service: "stale", env: "prod"})
+	stale.hourly.Store(3)
+	stale.monthly.Store(3)
+	stale.touch(now.Add(-2 * time.Hour))
+
+	report := exp.collectReport(now)
+
+	// idle and quiet have nothing in the hour and are left out of the lines
+	var services []string
+	for _, g := range report.Groups {
+		services = append(services, g.Service)
+	}
+	assert.ElementsMatch(t, []string{"busy", "stale"}, services)
+	assert.Equal(t, uint64(15), report.Total.Monthly.Total)
+
+	// stale is reported one last time, then evicted
+	_, ok := exp.statsMap.Load(groupingKey{service: "stale", env: "prod"})
+	assert.False(t, ok)
+	_, ok = exp.statsMap.Load(groupingKey{service: "quiet", env: "prod"})
+	assert.True(t, ok)
+}
+
+func TestRotateAndWrite_SuppressZeroKeepsFinishedPeriods(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	sinks, err := openSinks([]SinkConfig{{Type: sinkTypeFile, Path: path}})
+	require.NoError(t, err)
+	exp := &spanReportExporter{
+		sinks:        sinks,
+		logger:       componenttest.NewNopTelemetrySettings().Logger,
+		suppressZero: true,
+	}
+	stats := exp.statsFor(groupingKey{service: "batch", env: "prod"})
+	stats.daily.Store(500)
+	stats.monthly.Store(500)
+
+	// Nothing in the last hour, but the day ends: its final value is written
+	exp.lastExportTime = time.Date(2025, 12, 17, 23, 0, 0, 0, time.UTC)
+	exp.rotateAndWrite(time.Date(2025, 12, 18, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, uint64(0), stats.daily.Load())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "service:batch, env:prod | Hourly(Total:0, HTTP:0, SQL:0) | Daily(Total:500,")
+	assert.Contains(t, string(content), "service:*, env:* | Hourly(Total:0, HTTP:0, SQL:0) | Daily(Total:500,")
+
+	// The next hour of the same day, the group has nothing in the hour and is left out
+	exp.lastExportTime = time.Date(2025, 12, 18, 0, 0, 0, 0, time.UTC)
+	exp.rotateAndWrite(time.Date(2025, 12, 18, 1, 0, 0, 0, time.UTC))
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "[2025-12-18 00:59:59] service:batch")
+}
+
+func TestCollectReport_IdleTTLKeepsMergedGroup(t *testing.T) {
+	exp := &spanReportExporter{
+		logger:  componenttest.NewNopTelemetrySettings().Logger,
+		idleTTL: 2 * time.Hour,
+	}
+	exp.statsFor(groupingKey{service: "Order-API", env: "prod"}).monthly.Store(40)
+
+	_, err := exp.mergeService("Order-API", "order-api")
+	require.NoError(t, err)
+	report := exp.collectReport(time.Now())
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, uint64(40), report.Groups[0].Monthly.Total)
+	_, ok := exp.statsMap.Load(groupingKey{service: "order-api", env: "prod"})
+	assert.True(t, ok)
+}
+
+func TestEvictGroup_KeepsGroupTouchedAgain(t *testing.T) {
+	exp := &spanReportExporter{logger: componenttest.NewNopTelemetrySettings().Logger}
+	k := groupingKey{service: "order-api", env: "prod"}
+	now := time.Now()
+	s := exp.statsFor(k)
+	s.touch(now.Add(-2 * time.Hour))
+
+	// Data arrived after the idle check of the report
+	s.touch(now)
+	assert.False(t, exp.evictGroup(k, s, now.Add(-time.Hour), "idle_ttl"))
+	_, ok := exp.statsMap.Load(k)
+	assert.True(t, ok)
+
+	// A group replaced in the meantime is not removed
+	exp.statsMap.Store(k, &spanStats{})
+	assert.False(t, exp.evictGroup(k, s, now.Add(time.Hour), "idle_ttl"))
+	_, ok = exp.statsMap.Load(k)
+	assert.True(t, ok)
+}
+
+func TestAdminCommands_ForgetBaselines(t *testing.T) {
+	exp := &spanReportExporter{
+		logger:    componenttest.NewNopTelemetrySettings().Logger,
+		anomalies: newAnomalyDetector(AnomalyConfig{Enabled: true, Threshold: 3, Alpha: 0.3, MinSamples: 3}),
+	}
+	reset := groupingKey{service: "order-api", env: "prod"}
+	merged := groupingKey{service: "Auth-Svc", env: "prod"}
+	exp.statsFor(reset).hourly.Store(5)
+	exp.statsFor(merged).hourly.Store(7)
+
+	// The hour ends: both groups get earlier periods and a baseline
+	exp.lastExportTime = time.Date(2025, 12, 17, 9, 0, 0, 0, time.UTC)
+	exp.collectReport(time.Date(2025, 12, 17, 10, 0, 0, 0, time.UTC))
+	require.Contains(t, exp.prior.totals, reset)
+	require.Contains(t, exp.anomalies.baselines, merged)
+
+	require.NoError(t, exp.resetGroup(reset, nil))
+	_, err := exp.mergeService("Auth-Svc", "auth-svc")
+	require.NoError(t, err)
+	for _, k := range []groupingKey{reset, merged} {
+		assert.NotContains(t, exp.prior.totals, k)
+		assert.NotContains(t, exp.anomalies.baselines, k)
+	}
+}
@@ -2,7 +2,10 @@ package spanreportexporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"sort"
 	"sync"
@@ -11,13 +14,16 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 )
 
 type groupingKey struct {
-	service string
-	env     string
+	tenant    string // only set with tenant_header
+	namespace string // only set with hierarchy
+	service   string
+	env       string
 }
 
 type spanStats struct {
@@ -30,17 +36,133 @@ type spanStats struct {
 	sqlDaily    atomic.Uint64
 	httpMonthly atomic.Uint64
 	sqlMonthly  atomic.Uint64
+
+	// Log records, see ConsumeLogs
+	logHourly       atomic.Uint64
+	logDaily        atomic.Uint64
+	logMonthly      atomic.Uint64
+	logErrorHourly  atomic.Uint64
+	logErrorDaily   atomic.Uint64
+	logErrorMonthly atomic.Uint64
+
+	// Spans by kind, indexed by period
+	kinds [len(allPeriods)]spanKindStats
+
+	// Metric data points, indexed by period, see ConsumeMetrics
+	metrics  [len(allPeriods)]metricStats
+	seriesMu sync.Mutex // guards metricStats.series
+
+	lastSeen atomic.Int64 // Unix time in nanoseconds of the last data, see evictGroup
+}
+
+// spanKindStats counts the spans of one period by span kind. Spans of an
+// unspecified kind are counted as internal.
+type spanKindStats struct {
+	internal atomic.Uint64
+	server   atomic.Uint64
+	client   atomic.Uint64
+	producer atomic.Uint64
+	consumer atomic.Uint64
+}
+
+// counter returns the counter of a span kind.
+func (k *spanKindStats) counter(kind ptrace.SpanKind) *atomic.Uint64 {
+	switch kind {
+	case ptrace.SpanKindServer:
+		return &k.server
+	case ptrace.SpanKindClient:
+		return &k.client
+	case ptrace.SpanKindProducer:
+		return &k.producer
+	case ptrace.SpanKindConsumer:
+		return &k.consumer
+	default:
+		return &k.internal
+	}
+}
+
+// metricStats counts the metric data points of one period, by metric type.
+type metricStats struct {
+	dataPoints   atomic.Uint64
+	gauge        atomic.Uint64
+	sum          atomic.Uint64
+	histogram    atomic.Uint64
+	expHistogram atomic.Uint64
+	summary      atomic.Uint64
+	// series estimates the number of distinct series; nil until the first data point
+	series *hyperLogLog
 }
 
 type spanReportExporter struct {
-	path           string
-	verbose        bool
-	reportInterval time.Duration
-	logger         *zap.Logger
-	statsMap       sync.Map // map[groupingKey]*spanStats
-	stopCh         chan struct{}
-	lastExportTime time.Time
-	tui            bool
+	sinkConfigs     []SinkConfig
+	sinks           []namedSink
+	writeErrors     atomic.Uint64
+	verbose         bool
+	reportInterval  time.Duration
+	logger          *zap.Logger
+	statsMap        sync.Map // map[groupingKey]*spanStats, see statsFor
+	maxGroups       int
+	groupCounts     sync.Map // map[string]*atomic.Int64 by tenant, see groupCounter
+	overflow        overflowTracker
+	normalizer      *normalizer
+	hierarchy       bool
+	tenantHeader    string
+	maxTenants      int
+	tenantsMu       sync.Mutex
+	tenantGroups    map[string]int    // groups of each tenant with max_tenants, see reserveTenant
+	senders         *senderTracker    // nil unless senders is enabled
+	ownership       *ownershipWatcher // nil without an ownership file
+	ownershipReload time.Duration
+	idleTTL         time.Duration
+	suppressZero    bool
+	reportMu        sync.Mutex // serializes reports from the ticker, signals and shutdown
+	stopCh          chan struct{}
+	lastExportTime  time.Time
+	tui             bool
+	envSubtotals    bool
+	signals         []string // signals of the pipelines using this exporter, see hasSignal
+	history         *historyStore
+	prior           *priorStore
+	anomalies       *anomalyDetector
+	httpEndpoint    string
+	httpServer      *http.Server
+	stream          *streamHub
+	controlEndpoint string
+	controlServer   *http.Server
+	startTime       time.Time
+}
+
+type period int
+
+const (
+	periodHourly period = iota
+	periodDaily
+	periodMonthly
+)
+
+var allPeriods = [...]period{periodHourly, periodDaily, periodMonthly}
+
+func (p period) String() string {
+	switch p {
+	case periodDaily:
+		return "daily"
+	case periodMonthly:
+		return "monthly"
+	default:
+		return "hourly"
+	}
+}
+
+func parsePeriod(s string) (period, error) {
+	switch s {
+	case "hourly":
+		return periodHourly, nil
+	case "daily":
+		return periodDaily, nil
+	case "monthly":
+		return periodMonthly, nil
+	}
+	return 0, fmt.Errorf("unknown period %q (must be hourly, daily or monthly)", s)
 }
 
 type statsEntry struct {
@@ -48,37 +170,123 @@ type statsEntry struct {
 	stats *spanStats
 }
 
-func (e *spanReportExporter) ConsumeTraces(_ context.Context, td ptrace.Traces) error {
+// PeriodCounts holds the counters of a single period: Total/HTTP/SQL spans
+// with the spans by kind, log records with those of severity ERROR or higher, and metric data points
+// by metric type with the approximate number of distinct series.
+type PeriodCounts struct {
+	Total                uint64 `json:"total"`
+	HTTP                 uint64 `json:"http"`
+	SQL                  uint64 `json:"sql"`
+	Internal             uint64 `json:"internal"`
+	Server               uint64 `json:"server"`
+	Client               uint64 `json:"client"`
+	Producer             uint64 `json:"producer"`
+	Consumer             uint64 `json:"consumer"`
+	Logs                 uint64 `json:"logs"`
+	LogErrors            uint64 `json:"log_errors"`
+	DataPoints           uint64 `json:"data_points"`
+	Gauge                uint64 `json:"gauge"`
+	Sum                  uint64 `json:"sum"`
+	Histogram            uint64 `json:"histogram"`
+	ExponentialHistogram uint64 `json:"exponential_histogram"`
+	Summary              uint64 `json:"summary"`
+	Series               uint64 `json:"series"`
+}
+
+// GroupCounts is a point-in-time copy of the counters of one group.
+type GroupCounts struct {
+	// Tenant comes from the request metadata, only set with tenant_header
+	Tenant string `json:"tenant,omitempty"`
+	// Namespace is the service.namespace, only set with hierarchy
+	Namespace string `json:"namespace,omitempty"`
+	Service   string `json:"service"`
+	Env       string `json:"env"`
+	// Team and CostCenter are only set with an ownership file
+	Team       string       `json:"team,omitempty"`
+	CostCenter string       `json:"cost_center,omitempty"`
+	Hourly     PeriodCounts `json:"hourly"`
+	Daily      PeriodCounts `json:"daily"`
+	Monthly    PeriodCounts `json:"monthly"`
+	// Changes is only set in reports, see priorStore
+	Changes PeriodChanges `json:"changes,omitzero"`
+	// Anomaly is set when the last finished hour deviated from the baseline, see anomalyDetector
+	Anomaly *Anomaly `json:"anomaly,omitempty"`
+}
+
+// key returns the grouping key of the group.
+func (g GroupCounts) key() groupingKey {
+	return groupingKey{tenant: g.Tenant, namespace: g.Namespace, service: g.Service, env: g.Env}
+}
+
+// label returns the group as written in the text reports, e.g. "service:api, env:prod".
+func (k groupingKey) label() string {
+	return groupLabel(GroupCounts{Tenant: k.tenant, Namespace: k.namespace, Service: k.service, Env: k.env})
+}
+
+// counts returns a pointer to the counters of one period.
+func (g *GroupCounts) counts(p period) *PeriodCounts {
+	switch p {
+	case periodDaily:
+		return &g.Daily
+	case periodMonthly:
+		return &g.Monthly
+	default:
+		return &g.Hourly
+	}
+}
+
+// resourceKey returns the group of a resource, from its service name and deployment environment.
+func resourceKey(attrs pcommon.Map) groupingKey {
+	// Extract attributes
+	sName := "unknown"
+	if s, ok := attrs.Get("service.name"); ok {
+		sName = s.AsString()
+	}
+	eName := "unknown"
+	if e, ok := attrs.Get("deployment.environment.name"); ok {
+		eName = e.AsString()
+	} else if e, ok := attrs.Get("deployment.environment"); ok {
+		eName = e.AsString()
+	}
+	key := groupingKey{
+		service: sName,
+		env:     eName,
+	}
+	if key.service == "" {
+		key.service = "unknown"
+	}
+	if key.env == "" {
+		key.env = "unknown"
+	}
+	return key
+}
+
+// groupKey returns the group of a resource, with its namespace if hierarchy is
+// enabled and the tenant of the request if tenant_header is set, after the
+// normalize rules.
+func (e *spanReportExporter) groupKey(ctx context.Context, attrs pcommon.Map) groupingKey {
+	key := e.normalizer.apply(resourceKey(attrs))
+	if e.tenantHeader != "" {
+		key.tenant = tenantFromContext(ctx, e.tenantHeader)
+	}
+	if e.hierarchy {
+		key.namespace = "unknown"
+		if ns, ok := attrs.Get("service.namespace"); ok && ns.AsString() != "" {
+			key.namespace = ns.AsString()
+		}
+	}
+	return key
+}
+
+func (e *spanReportExporter) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	e.senders.add(ctx, SenderCounts{Spans: uint64(td.SpanCount())})
 	rss := td.ResourceSpans()
 	for i := 0; i < rss.Len(); i++ {
 		rs := rss.At(i)
-		attrs := rs.Resource().Attributes()
-
-		// Extract attributes
-		sName := "unknown"
-		if s, ok := attrs.Get("service.name"); ok {
-			sName = s.AsString()
-		}
-		eName := "unknown"
-		if e, ok := attrs.Get("deployment.environment.name"); ok {
-			eName = e.AsString()
-		} else if e, ok := attrs.Get("deployment.environment"); ok {
-			eName = e.AsString()
-		}
-		key := groupingKey{
-			service: sName,
-			env:     eName,
-		}
-		if key.service == "" {
-			key.service = "unknown"
-		}
-		if key.env == "" {
-			key.env = "unknown"
-		}
+		key := e.groupKey(ctx, rs.Resource().Attributes())
 
 		// Retrieve or initialize the statistics object
-		val, _ := e.statsMap.LoadOrStore(key, &spanStats{})
-		stats := val.(*spanStats)
+		stats := e.statsFor(key)
 
 		ilss := rs.ScopeSpans()
 		for j := 0; j < ilss.Len(); j++ {
@@ -90,6 +298,9 @@ func (e *spanReportExporter) ConsumeTraces(_ context.Context, td ptrace.Traces)
 				stats.hourly.Add(1)
 				stats.daily.Add(1)
 				stats.monthly.Add(1)
+				for p := range stats.kinds {
+					stats.kinds[p].counter(span.Kind()).Add(1)
+				}
 
 				// Categorize by Kind and Attributes
 				attrs := span.Attributes()
@@ -156,82 +367,281 @@ func (e *spanReportExporter) startReporting() {
 }
 
 func (e *spanReportExporter) rotateAndWrite(now time.Time) {
+	e.reportMu.Lock()
+	defer e.reportMu.Unlock()
+
 	// 1. Calculate and update stats (Logic part)
-	lines := e.generateReportLines(now)
-	if len(lines) == 0 {
+	report := e.collectReport(now)
+	if len(report.Groups) == 0 && report.Total.isZero() {
+		e.lastExportTime = now
 		return
 	}
 
-	// 2. File I/O (Side effect part)
-	f, err := os.OpenFile(e.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		e.logger.Error("Failed to open report file", zap.Error(err))
-		return
+	// 2. Output (Side effect part)
+	for _, s := range e.sinks {
+		if err := s.Write(context.Background(), report); err != nil {
+			e.writeErrors.Add(1)
+			fields := []zap.Field{zap.String("sink", s.name), zap.Error(err)}
+			if ps, ok := s.ReportSink.(pendingReporter); ok {
+				// The lines are kept in memory and retried with the next report
+				pending, dropped := ps.status()
+				fields = append(fields, zap.Int("pending_lines", pending), zap.Uint64("dropped_lines", dropped))
+			}
+			e.logger.Error("Failed to write report", fields...)
+		}
 	}
-	defer f.Close()
-
-	for _, line := range lines {
-		f.WriteString(line)
-		if e.tui == false {
-			fmt.Print(line)
+	for _, g := range report.Groups {
+		if g.Anomaly != nil {
+			e.notifyAnomaly(*g.Anomaly)
 		}
 	}
+	e.warnOverflow()
+	if e.history != nil {
+		e.history.add(now, report.Groups)
+	}
 
 	// Update the last export time
 	e.lastExportTime = now
 }
 
+// notifyAnomaly logs a warning and sends an event to the sinks that accept events.
+func (e *spanReportExporter) notifyAnomaly(a Anomaly) {
+	e.logger.Warn("Span volume anomaly",
+		zap.String("tenant", a.Tenant),
+		zap.String("namespace", a.Namespace),
+		zap.String("service", a.Service),
+		zap.String("environment", a.Env),
+		zap.Time("hour", a.Time),
+		zap.Uint64("count", a.Count),
+		zap.Float64("expected", a.Expected),
+		zap.Float64("stddev", a.StdDev),
+		zap.Float64("score", a.Score),
+	)
+	ev := Event{Type: EventTypeAnomaly, Time: a.Time, Anomaly: &a}
+	for _, s := range e.sinks {
+		es, ok := s.ReportSink.(EventSink)
+		if !ok {
+			continue
+		}
+		if err := es.WriteEvent(context.Background(), ev); err != nil {
+			e.writeErrors.Add(1)
+			e.logger.Error("Failed to send event", zap.String("sink", s.name), zap.Error(err))
+		}
+	}
+}
+
 // generateReportLines updates internal counters and returns formatted strings for the report.
 // This method is now easy to test without creating files.
 func (e *spanReportExporter) generateReportLines(now time.Time) []string {
-	var lines []string
-	displayTime := now.Add(-1 * time.Second).Format("2006-01-02 15:04:05")
+	lines, _ := formatTextLines(e.collectReport(now))
+	return lines
+}
+
+// collectReport returns a snapshot of every group. The counters whose period
+// has ended are reset, and the report contains their final values, which are
+// also remembered to compute the changes versus earlier periods.
+func (e *spanReportExporter) collectReport(now time.Time) Report {
+	report := Report{Time: reportTime(now), Signals: e.signals}
+	if e.prior == nil {
+		e.prior = newPriorStore()
+	}
 
 	// Pre-calculate boundary flags to avoid checking them inside the loop
-	isNewHour := !e.lastExportTime.IsZero() && now.Hour() != e.lastExportTime.Hour()
-	isNewDay := !e.lastExportTime.IsZero() && now.Day() != e.lastExportTime.Day()
-	isNewMonth := !e.lastExportTime.IsZero() && now.Month() != e.lastExportTime.Month()
+	finished := map[period]bool{}
+	if !e.lastExportTime.IsZero() {
+		finished[periodHourly] = now.Hour() != e.lastExportTime.Hour()
+		finished[periodDaily] = now.Day() != e.lastExportTime.Day()
+		finished[periodMonthly] = now.Month() != e.lastExportTime.Month()
+	}
+	// The start of the period each counter belongs to: the period of the previous
+	// report for the finished ones, the current period for the others
+	starts := map[period]time.Time{}
+	for _, p := range allPeriods {
+		if finished[p] {
+			starts[p] = periodStart(p, e.lastExportTime)
+		} else {
+			starts[p] = periodStart(p, report.Time)
+		}
+	}
 
-	e.statsMap.Range(func(keyAny, valAny any) bool {
-		k := keyAny.(groupingKey)
-		s := valAny.(*spanStats)
-
-		// Conditional reset for Hourly/Daily/Monthly
-		if isNewHour {
-			s.hourly.Store(0)
-			s.httpHourly.Store(0)
-			s.sqlHourly.Store(0)
+	// Sorted by tenant first, so that the lines of each tenant are contiguous
+	for _, entry := range e.getSortedEntries() {
+		k, s := entry.key, entry.stats
+
+		row := GroupCounts{Tenant: k.tenant, Namespace: k.namespace, Service: k.service, Env: k.env}
+		for _, p := range allPeriods {
+			if finished[p] {
+				// Conditional reset for Hourly/Daily/Monthly
+				*row.counts(p) = s.take(p)
+			} else {
+				*row.counts(p) = s.load(p)
+			}
 		}
-		if isNewDay {
-			s.daily.Store(0)
-			s.httpDaily.Store(0)
-			s.sqlDaily.Store(0)
+		if finished[periodMonthly] && row.isZero() &&
+			e.evictGroup(k, s, starts[periodMonthly], "idle for a whole month") {
+			// Nothing in the month that has just finished
+			continue
+		}
+		for _, p := range allPeriods {
+			if finished[p] {
+				e.prior.record(k, p, starts[p], *row.counts(p))
+			}
 		}
-		if isNewMonth {
-			s.monthly.Store(0)
-			s.httpMonthly.Store(0)
-			s.sqlMonthly.Store(0)
+		row.Changes = e.prior.changes(k, row, starts, finished)
+		if finished[periodHourly] && e.anomalies != nil {
+			row.Anomaly = e.anomalies.observe(k, starts[periodHourly], row.Hourly.Total)
 		}
 
-		// Load current values
-		h, d, m := s.hourly.Load(), s.daily.Load(), s.monthly.Load()
-		hHTTP, dHTTP, mHTTP := s.httpHourly.Load(), s.httpDaily.Load(), s.httpMonthly.Load()
-		hSQL, dSQL, mSQL := s.sqlHourly.Load(), s.sqlDaily.Load(), s.sqlMonthly.Load()
-
-		line := fmt.Sprintf("[%s] service:%s, env:%s | "+
-			"Hourly(Total:%d, HTTP:%d, SQL:%d) | "+
-			"Daily(Total:%d, HTTP:%d, SQL:%d) | "+
-			"Monthly(Total:%d, HTTP:%d, SQL:%d)\n",
-			displayTime, k.service, k.env,
-			h, hHTTP, hSQL,
-			d, dHTTP, dSQL,
-			m, mHTTP, mSQL,
-		)
-		lines = append(lines, line)
-		return true
-	})
+		report.Groups = append(report.Groups, row)
+		if e.idleTTL > 0 {
+			// The last values are in this report
+			e.evictGroup(k, s, now.Add(-e.idleTTL), "idle_ttl")
+		}
+	}
+	e.setOwners(report.Groups)
+	report.Total, report.EnvTotals = summarize(report.Groups, e.envSubtotals)
+	report.Subtotals = subtotalsByNamespace(report.Groups)
+	report.TenantTotals = totalsByTenant(report.Groups)
+	report.Teams = rollUpTeams(report.Groups)
+	report.Senders = e.senders.periods(finished)
+	if e.suppressZero {
+		// The totals still include the daily and monthly counts of these groups
+		report.Groups = withoutZeroGroups(report.Groups, finished)
+	}
 
-	return lines
+	return report
+}
+
+// reportTime returns the time a report is labeled with: one second before the tick,
+// so that an hourly report is stamped as the last second of the hour it covers.
+func reportTime(now time.Time) time.Time {
+	return now.Add(-1 * time.Second)
+}
+
+// counters returns the counters of one period, in the order read by read.
+func (s *spanStats) counters(p period) []*atomic.Uint64 {
+	var c []*atomic.Uint64
+	switch p {
+	case periodDaily:
+		c = []*atomic.Uint64{&s.daily, &s.httpDaily, &s.sqlDaily, &s.logDaily, &s.logErrorDaily}
+	case periodMonthly:
+		c = []*atomic.Uint64{&s.monthly, &s.httpMonthly, &s.sqlMonthly, &s.logMonthly, &s.logErrorMonthly}
+	default:
+		c = []*atomic.Uint64{&s.hourly, &s.httpHourly, &s.sqlHourly, &s.logHourly, &s.logErrorHourly}
+	}
+	m := &s.metrics[p]
+	c = append(c, &m.dataPoints, &m.gauge, &m.sum, &m.histogram, &m.expHistogram, &m.summary)
+	k := &s.kinds[p]
+	return append(c, &k.internal, &k.server, &k.client, &k.producer, &k.consumer)
+}
+
+// read builds the PeriodCounts of one period, applying f to each counter.
+func (s *spanStats) read(p period, f func(*atomic.Uint64) uint64) PeriodCounts {
+	c := s.counters(p)
+	return PeriodCounts{
+		Total:                f(c[0]),
+		HTTP:                 f(c[1]),
+		SQL:                  f(c[2]),
+		Logs:                 f(c[3]),
+		LogErrors:            f(c[4]),
+		DataPoints:           f(c[5]),
+		Gauge:                f(c[6]),
+		Sum:                  f(c[7]),
+		Histogram:            f(c[8]),
+		ExponentialHistogram: f(c[9]),
+		Summary:              f(c[10]),
+		Internal:             f(c[11]),
+		Server:               f(c[12]),
+		Client:               f(c[13]),
+		Producer:             f(c[14]),
+		Consumer:             f(c[15]),
+	}
+}
+
+// reset sets the counters of one period to 0.
+func (s *spanStats) reset(p period) {
+	for _, c := range s.counters(p) {
+		c.Store(0)
+	}
+	s.takeSeries(p)
+}
+
+// load returns the counters of one period.
+func (s *spanStats) load(p period) PeriodCounts {
+	c := s.read(p, (*atomic.Uint64).Load)
+	s.seriesMu.Lock()
+	if h := s.metrics[p].series; h != nil {
+		c.Series = h.estimate()
+	}
+	s.seriesMu.Unlock()
+	return c
+}
+
+// take returns the counters of one period and sets them to 0.
+func (s *spanStats) take(p period) PeriodCounts {
+	c := s.read(p, func(c *atomic.Uint64) uint64 { return c.Swap(0) })
+	if h := s.takeSeries(p); h != nil {
+		c.Series = h.estimate()
+	}
+	return c
+}
+
+// addSeries records the series hashes of data points in every period.
+func (s *spanStats) addSeries(hashes []uint64) {
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+	for p := range s.metrics {
+		h := s.metrics[p].series
+		if h == nil {
+			h = &hyperLogLog{}
+			s.metrics[p].series = h
+		}
+		for _, hash := range hashes {
+			h.add(hash)
+		}
+	}
+}
+
+// takeSeries returns the series sketch of one period and starts a new one.
+func (s *spanStats) takeSeries(p period) *hyperLogLog {
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+	h := s.metrics[p].series
+	s.metrics[p].series = nil
+	return h
+}
+
+// moveTo adds all counters to dst and sets them to 0. dst is considered to
+// have received data whenever s did.
+func (s *spanStats) moveTo(dst *spanStats) {
+	dst.seen(s.lastSeen.Load())
+	for _, p := range allPeriods {
+		src, d := s.counters(p), dst.counters(p)
+		for i := range src {
+			d[i].Add(src[i].Swap(0))
+		}
+		if h := s.takeSeries(p); h != nil {
+			dst.seriesMu.Lock()
+			if dst.metrics[p].series == nil {
+				dst.metrics[p].series = &hyperLogLog{}
+			}
+			dst.metrics[p].series.merge(h)
+			dst.seriesMu.Unlock()
+		}
+	}
+}
+
+// snapshot loads the current values of all counters.
+func (s *spanStats) snapshot(k groupingKey) GroupCounts {
+	return GroupCounts{
+		Tenant:    k.tenant,
+		Namespace: k.namespace,
+		Service:   k.service,
+		Env:       k.env,
+		Hourly:    s.load(periodHourly),
+		Daily:     s.load(periodDaily),
+		Monthly:   s.load(periodMonthly),
+	}
 }
 
 func (e *spanReportExporter) getSortedEntries() []statsEntry {
@@ -245,8 +655,14 @@ func (e *spanReportExporter) getSortedEntries() []statsEntry {
 		return true
 	})
 
-	// Sort by service name (ascending) -> environment name (ascending)
+	// Sort by tenant -> namespace -> service name -> environment name (all ascending)
 	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].key.tenant != entries[j].key.tenant {
+			return entries[i].key.tenant < entries[j].key.tenant
+		}
+		if entries[i].key.namespace != entries[j].key.namespace {
+			return entries[i].key.namespace < entries[j].key.namespace
+		}
 		if entries[i].key.service != entries[j].key.service {
 			return entries[i].key.service < entries[j].key.service
 		}
@@ -257,6 +673,36 @@ func (e *spanReportExporter) getSortedEntries() []statsEntry {
 }
 
 func (e *spanReportExporter) Start(_ context.Context, _ component.Host) error {
+	e.startTime = time.Now()
+	if e.ownership != nil {
+		if _, err := e.ownership.reload(); err != nil {
+			return fmt.Errorf("failed to load the ownership file: %w", err)
+		}
+		e.watchOwnership(e.ownershipReload)
+	}
+	if e.sinkConfigs != nil {
+		sinks, err := openSinks(e.sinkConfigs)
+		if err != nil {
+			return err
+		}
+		e.sinks = sinks
+	}
+	if e.httpEndpoint != "" {
+		ln, err := net.Listen("tcp", e.httpEndpoint)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", e.httpEndpoint, err)
+		}
+		e.stream = newStreamHub()
+		e.startStreaming()
+		e.httpServer = e.serveHTTP(ln, e.newAPIHandler())
+	}
+	if e.controlEndpoint != "" {
+		ln, err := listenControl(e.controlEndpoint)
+		if err != nil {
+			return fmt.Errorf("failed to listen on control endpoint %s: %w", e.controlEndpoint, err)
+		}
+		e.controlServer = e.serveHTTP(ln, e.newControlHandler())
+	}
 	if e.tui {
 		go func() {
 			p := tea.NewProgram(NewTUIModel(e), tea.WithAltScreen())
@@ -268,12 +714,31 @@ func (e *spanReportExporter) Start(_ context.Context, _ component.Host) error {
 		}()
 	}
 	e.startReporting()
+	e.handleSignals()
 	return nil
 }
 
-func (e *spanReportExporter) Shutdown(_ context.Context) error {
+// serveHTTP serves handler on ln in the background until the returned server is shut down.
+func (e *spanReportExporter) serveHTTP(ln net.Listener, handler http.Handler) *http.Server {
+	srv := &http.Server{Handler: handler}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logger.Error("HTTP server stopped", zap.String("address", ln.Addr().String()), zap.Error(err))
+		}
+	}()
+	return srv
+}
+
+func (e *spanReportExporter) Shutdown(ctx context.Context) error {
 	close(e.stopCh)
+	if e.httpServer != nil {
+		e.httpServer.Shutdown(ctx)
+	}
+	if e.controlServer != nil {
+		e.controlServer.Shutdown(ctx)
+	}
 	e.rotateAndWrite(time.Now())
+	closeSinks(e.sinks)
 	e.logger.Info("SHUTDOWN")
 	return nil
 }
@@ -10,23 +10,19 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestConsumeTraces_Counting(t *testing.T) {
-	// 1. Create a temporary file for testing
-	tmpFile, err := os.CreateTemp("", "span_report_test.txt")
-	require.NoError(t, err)
-	defer os.Remove(tmpFile.Name())
-
-	// 2. Instantiate the Exporter
+	// 1. Instantiate the Exporter
 	exp := &spanReportExporter{
-		path:    tmpFile.Name(),
 		verbose: true,
 		logger:  componenttest.NewNopTelemetrySettings().Logger,
 		stopCh:  make(chan struct{}),
 	}
 
-	// 3. Create test data
+	// 2. Create test data
 	td := ptrace.NewTraces()
 	rs := td.ResourceSpans().AppendEmpty()
 	rs.Resource().Attributes().PutStr("service.name", "test-service")
@@ -36,12 +32,12 @@ func TestConsumeTraces_Counting(t *testing.T) {
 	scope.Spans().AppendEmpty() // First span
 	scope.Spans().AppendEmpty() // Second span
 
-	// 4. Execution
+	// 3. Execution
 	ctx := context.Background()
-	err = exp.ConsumeTraces(ctx, td)
+	err := exp.ConsumeTraces(ctx, td)
 	assert.NoError(t, err)
 
-	// 5. Validation: Check if values are correctly stored in statsMap
+	// 4. Validation: Check if values are correctly stored in statsMap
 	key := groupingKey{service: "test-service", env: "dev"}
 	val, ok := exp.statsMap.Load(key)
 	assert.True(t, ok, "statsMap should have the key")
@@ -57,9 +53,11 @@ func TestRotateAndWrite_ResetLogic(t *testing.T) {
 	tmpFile, err := os.CreateTemp("", "span_reset_test.txt")
 	require.NoError(t, err)
 	defer os.Remove(tmpFile.Name())
+	sinks, err := openSinks([]SinkConfig{{Type: sinkTypeFile, Path: tmpFile.Name()}})
+	require.NoError(t, err)
 
 	exp := &spanReportExporter{
-		path:   tmpFile.Name(),
+		sinks:  sinks,
 		logger: componenttest.NewNopTelemetrySettings().Logger,
 	}
 
@@ -86,12 +84,7 @@ func TestRotateAndWrite_ResetLogic(t *testing.T) {
 
 func TestConsumeTraces_Categorization(t *testing.T) {
 	// 1. Setup exporter
-	tmpFile, err := os.CreateTemp("", "category_test")
-	require.NoError(t, err)
-	defer os.Remove(tmpFile.Name())
-
 	exp := &spanReportExporter{
-		path:   tmpFile.Name(),
 		logger: componenttest.NewNopTelemetrySettings().Logger,
 	}
 
@@ -117,7 +110,7 @@ func TestConsumeTraces_Categorization(t *testing.T) {
 	s3.SetName("internal-work")
 
 	// 3. Consume
-	err = exp.ConsumeTraces(context.Background(), td)
+	err := exp.ConsumeTraces(context.Background(), td)
 	require.NoError(t, err)
 
 	// 4. Verify memory stats
@@ -136,13 +129,34 @@ func TestConsumeTraces_Categorization(t *testing.T) {
 	assert.Equal(t, uint64(1), stats.sqlMonthly.Load())
 }
 
+func TestConsumeTraces_SpanKinds(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop()}
+
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "test-svc")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, kind := range []ptrace.SpanKind{
+		ptrace.SpanKindInternal, ptrace.SpanKindInternal, ptrace.SpanKindUnspecified,
+		ptrace.SpanKindServer, ptrace.SpanKindClient, ptrace.SpanKindClient,
+		ptrace.SpanKindProducer, ptrace.SpanKindConsumer,
+	} {
+		spans.AppendEmpty().SetKind(kind)
+	}
+	require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+
+	val, ok := exp.statsMap.Load(groupingKey{service: "test-svc", env: "unknown"})
+	require.True(t, ok)
+	for _, p := range allPeriods {
+		c := val.(*spanStats).load(p)
+		// Unspecified spans are counted as internal
+		assert.Equal(t, PeriodCounts{Total: 8, Internal: 3, Server: 1, Client: 2, Producer: 1, Consumer: 1}, c, p.String())
+	}
+}
+
 func TestRotateAndWrite_CumulativeResets(t *testing.T) {
 	// Setup
-	tmpFile, err := os.CreateTemp("", "category_test")
-	require.NoError(t, err)
-	defer os.Remove(tmpFile.Name())
 	exp := &spanReportExporter{
-		path:   tmpFile.Name(),
 		logger: componenttest.NewNopTelemetrySettings().Logger,
 	}
 	key := groupingKey{service: "svc", env: "env"}
@@ -207,3 +221,44 @@ func TestHumanize(t *testing.T) {
 		})
 	}
 }
+
+func TestRotateAndWrite_FlushKeepsCountersWithinPeriods(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "span_flush_test.txt")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	sinks, err := openSinks([]SinkConfig{{Type: sinkTypeFile, Path: tmpFile.Name()}})
+	require.NoError(t, err)
+
+	core, logs := observer.New(zap.InfoLevel)
+	exp := &spanReportExporter{
+		sinks:  sinks,
+		logger: zap.New(core),
+	}
+	key := groupingKey{service: "svc", env: "env"}
+	stats := &spanStats{}
+	stats.hourly.Store(10)
+	stats.monthly.Store(1000)
+	stats.kinds[periodHourly].server.Store(10)
+	exp.statsMap.Store(key, stats)
+
+	// A flush within the same hour (as flushReport does at time.Now()) writes
+	// a report but keeps the counters
+	exp.lastExportTime = time.Date(2025, 12, 18, 9, 0, 0, 0, time.UTC)
+	exp.rotateAndWrite(time.Date(2025, 12, 18, 9, 20, 0, 0, time.UTC))
+	assert.Equal(t, uint64(10), stats.hourly.Load())
+	content, err := os.ReadFile(tmpFile.Name())
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "service:svc, env:env | Hourly(Total:10,")
+
+	// The snapshot contains all periods of every group
+	exp.logSnapshot()
+	entries := logs.FilterMessage("Snapshot group").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "svc", fields["service"])
+	assert.Equal(t, uint64(10), fields["hourly_total"])
+	assert.Equal(t, uint64(10), fields["hourly_server"])
+	assert.Equal(t, uint64(0), fields["daily_server"])
+	assert.Equal(t, uint64(1000), fields["monthly_total"])
+}
@@ -2,6 +2,8 @@ package spanreportexporter
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
@@ -11,6 +13,11 @@ import (
 
 const typeStr = "spanreportexporter"
 
+const defaultMaxPendingLines = 10000
+
+// defaultMaxTenants bounds the tenants a client can create with tenant_header.
+const defaultMaxTenants = 100
+
 var componentType = component.MustNewType("spanreportexporter")
 
 func NewFactory() exporter.Factory {
@@ -18,45 +25,271 @@ func NewFactory() exporter.Factory {
 		componentType,
 		createDefaultConfig,
 		exporter.WithTraces(createTracesExporter, component.StabilityLevelAlpha),
+		exporter.WithLogs(createLogsExporter, component.StabilityLevelAlpha),
+		exporter.WithMetrics(createMetricsExporter, component.StabilityLevelAlpha),
 	)
 }
 
 type Config struct {
-	FilePath       string `mapstructure:"path"`
-	Verbose        bool   `mapstructure:"verbose"`
-	ReportInterval string `mapstructure:"report_interval"`
-	TUI            bool   `mapstructure:"tui"`
+	// Sinks lists the outputs of the reports. When empty, the outputs are derived from
+	// path, sqlite_path and tui (see sinkConfigs).
+	Sinks            []SinkConfig    `mapstructure:"sinks"`
+	FilePath         string          `mapstructure:"path"`
+	Template         string          `mapstructure:"template"`
+	EnvSubtotals     bool            `mapstructure:"env_subtotals"`
+	Normalize        NormalizeConfig `mapstructure:"normalize"`
+	Hierarchy        bool            `mapstructure:"hierarchy"`     // groups by service.namespace, then service and env
+	TenantHeader     string          `mapstructure:"tenant_header"` // request metadata with the tenant, e.g. X-Tenant-ID
+	MaxTenants       int             `mapstructure:"max_tenants"`   // further tenants are counted under __overflow__; 0 means no limit
+	Ownership        OwnershipConfig `mapstructure:"ownership"`
+	Senders          SendersConfig   `mapstructure:"senders"`
+	MaxGroups        int             `mapstructure:"max_groups"`    // further groups are counted under __overflow__; 0 means no limit
+	IdleTTL          string          `mapstructure:"idle_ttl"`      // groups are also evicted after receiving nothing for this long
+	SuppressZero     bool            `mapstructure:"suppress_zero"` // groups with nothing in the hour are left out of the report lines, unless a day or month just ended
+	Anomaly          AnomalyConfig   `mapstructure:"anomaly"`
+	Rotation         RotationConfig  `mapstructure:"rotation"`
+	Fsync            bool            `mapstructure:"fsync"`
+	MaxPendingLines  int             `mapstructure:"max_pending_lines"`
+	SQLitePath       string          `mapstructure:"sqlite_path"`
+	Verbose          bool            `mapstructure:"verbose"`
+	ReportInterval   string          `mapstructure:"report_interval"`
+	TUI              bool            `mapstructure:"tui"`
+	HTTPEndpoint     string          `mapstructure:"http_endpoint"`
+	HistoryRetention string          `mapstructure:"history_retention"`
+	ControlEndpoint  string          `mapstructure:"control_endpoint"`
+}
+
+// Validate checks the sinks, templates and anomaly detection, so that a typo is reported when the collector starts.
+func (c *Config) Validate() error {
+	if err := c.Anomaly.validate(); err != nil {
+		return err
+	}
+	if c.MaxGroups < 0 {
+		return fmt.Errorf("max_groups must not be negative")
+	}
+	if c.MaxTenants < 0 {
+		return fmt.Errorf("max_tenants must not be negative")
+	}
+	if c.MaxPendingLines < 0 {
+		return fmt.Errorf("max_pending_lines must not be negative")
+	}
+	if c.IdleTTL != "" {
+		if d, err := time.ParseDuration(c.IdleTTL); err != nil || d < 0 {
+			return fmt.Errorf("invalid idle_ttl %q", c.IdleTTL)
+		}
+	}
+	if err := c.Senders.validate(); err != nil {
+		return err
+	}
+	if err := c.Ownership.validate(); err != nil {
+		return err
+	}
+	if err := c.Normalize.validate(); err != nil {
+		return err
+	}
+	if c.Template != "" {
+		if _, err := parseReportTemplate(c.Template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	names := map[string]bool{}
+	for i, sc := range c.Sinks {
+		if err := sc.validate(); err != nil {
+			return fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+		if names[sc.name()] {
+			return fmt.Errorf("sinks[%d]: duplicate sink name %q", i, sc.name())
+		}
+		names[sc.name()] = true
+		if sc.Type == sinkTypeStdout && c.TUI {
+			return fmt.Errorf("sinks[%d]: the stdout sink cannot be used together with tui", i)
+		}
+	}
+	return nil
+}
+
+// sinkConfigs returns the configured sinks, or the ones equivalent to the
+// top-level options: the report file, stdout without the TUI, and SQLite.
+func (c *Config) sinkConfigs() []SinkConfig {
+	if len(c.Sinks) > 0 {
+		return c.Sinks
+	}
+	sinks := []SinkConfig{}
+	if c.FilePath != "" {
+		sinks = append(sinks, SinkConfig{
+			Type:            sinkTypeFile,
+			Path:            c.FilePath,
+			Rotation:        c.Rotation,
+			Fsync:           c.Fsync,
+			MaxPendingLines: c.MaxPendingLines,
+			Template:        c.Template,
+		})
+	}
+	if !c.TUI {
+		sinks = append(sinks, SinkConfig{Type: sinkTypeStdout, Template: c.Template})
+	}
+	if c.SQLitePath != "" {
+		sinks = append(sinks, SinkConfig{Type: sinkTypeSQLite, Path: c.SQLitePath})
+	}
+	return sinks
 }
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		FilePath:       "./span_report.txt",
-		Verbose:        false,
-		ReportInterval: "1h",
-		TUI:            true,
+		FilePath:         "./span_report.txt",
+		Verbose:          false,
+		ReportInterval:   "1h",
+		TUI:              true,
+		MaxPendingLines:  defaultMaxPendingLines,
+		HistoryRetention: "744h",
+		MaxTenants:       defaultMaxTenants,
+		Anomaly: AnomalyConfig{
+			Threshold:  3,
+			Alpha:      0.3,
+			MinSamples: 3,
+		},
+		Senders: SendersConfig{
+			TopN: 10,
+		},
 	}
 }
 
 func createTracesExporter(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Traces, error) {
-	c := cfg.(*Config)
+	shared := getSharedExporter(set, cfg.(*Config), signalTraces)
+	return exporterhelper.NewTraces(
+		ctx,
+		set,
+		cfg,
+		shared.exp.ConsumeTraces,
+		exporterhelper.WithStart(shared.start),
+		exporterhelper.WithShutdown(shared.shutdown),
+	)
+}
+
+func createLogsExporter(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Logs, error) {
+	shared := getSharedExporter(set, cfg.(*Config), signalLogs)
+	return exporterhelper.NewLogs(
+		ctx,
+		set,
+		cfg,
+		shared.exp.ConsumeLogs,
+		exporterhelper.WithStart(shared.start),
+		exporterhelper.WithShutdown(shared.shutdown),
+	)
+}
+
+func createMetricsExporter(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Metrics, error) {
+	shared := getSharedExporter(set, cfg.(*Config), signalMetrics)
+	return exporterhelper.NewMetrics(
+		ctx,
+		set,
+		cfg,
+		shared.exp.ConsumeMetrics,
+		exporterhelper.WithStart(shared.start),
+		exporterhelper.WithShutdown(shared.shutdown),
+	)
+}
+
+// sharedExporter is the single spanReportExporter behind the traces, logs and metrics
+// pipelines of one exporter configuration, so that all signals end up in the
+// same report. It is started by the first pipeline and shut down by the last one.
+type sharedExporter struct {
+	cfg     *Config
+	exp     *spanReportExporter
+	mu      sync.Mutex
+	started int
+}
+
+var (
+	sharedExportersMu sync.Mutex
+	sharedExporters   = map[*Config]*sharedExporter{}
+)
+
+// getSharedExporter returns the exporter of cfg, creating it for the first signal.
+func getSharedExporter(set exporter.Settings, cfg *Config, signal string) *sharedExporter {
+	sharedExportersMu.Lock()
+	defer sharedExportersMu.Unlock()
+
+	shared, ok := sharedExporters[cfg]
+	if !ok {
+		shared = &sharedExporter{cfg: cfg, exp: newSpanReportExporter(set, cfg)}
+		sharedExporters[cfg] = shared
+	}
+	if !shared.exp.hasSignal(signal) {
+		shared.exp.signals = append(shared.exp.signals, signal)
+	}
+	return shared
+}
+
+func (s *sharedExporter) start(ctx context.Context, host component.Host) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started++
+	if s.started > 1 {
+		return nil
+	}
+	return s.exp.Start(ctx, host)
+}
+
+func (s *sharedExporter) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started == 0 {
+		return nil
+	}
+	s.started--
+	if s.started > 0 {
+		return nil
+	}
+	sharedExportersMu.Lock()
+	delete(sharedExporters, s.cfg)
+	sharedExportersMu.Unlock()
+	return s.exp.Shutdown(ctx)
+}
+
+func newSpanReportExporter(set exporter.Settings, c *Config) *spanReportExporter {
 	interval, _ := time.ParseDuration(c.ReportInterval)
 	if interval <= 0 {
 		interval = time.Hour
 	}
+	retention, _ := time.ParseDuration(c.HistoryRetention)
+	if retention <= 0 {
+		retention = 31 * 24 * time.Hour
+	}
+	idleTTL, _ := time.ParseDuration(c.IdleTTL)
 	exp := &spanReportExporter{
-		path:           c.FilePath,
-		verbose:        c.Verbose,
-		reportInterval: interval,
-		logger:         set.Logger,
-		tui:            c.TUI,
-		stopCh:         make(chan struct{}),
+		sinkConfigs:     c.sinkConfigs(),
+		verbose:         c.Verbose,
+		reportInterval:  interval,
+		logger:          set.Logger,
+		tui:             c.TUI,
+		envSubtotals:    c.EnvSubtotals,
+		maxGroups:       c.MaxGroups,
+		hierarchy:       c.Hierarchy,
+		tenantHeader:    c.TenantHeader,
+		maxTenants:      c.MaxTenants,
+		idleTTL:         idleTTL,
+		suppressZero:    c.SuppressZero,
+		stopCh:          make(chan struct{}),
+		history:         newHistoryStore(retention),
+		prior:           newPriorStore(),
+		httpEndpoint:    c.HTTPEndpoint,
+		controlEndpoint: c.ControlEndpoint,
 	}
-	return exporterhelper.NewTraces(
-		ctx,
-		set,
-		cfg,
-		exp.ConsumeTraces,
-		exporterhelper.WithStart(exp.Start),
-		exporterhelper.WithShutdown(exp.Shutdown),
-	)
+	if c.Ownership.Path != "" {
+		exp.ownership = &ownershipWatcher{path: c.Ownership.Path}
+		exp.ownershipReload, _ = time.ParseDuration(c.Ownership.ReloadInterval)
+		if exp.ownershipReload <= 0 {
+			exp.ownershipReload = 30 * time.Second
+		}
+	}
+	// Validate has checked the patterns
+	exp.normalizer, _ = newNormalizer(c.Normalize)
+	if c.Senders.Enabled {
+		exp.senders = newSenderTracker(c.Senders)
+	}
+	if c.Anomaly.Enabled {
+		exp.anomalies = newAnomalyDetector(c.Anomaly)
+	}
+	return exp
 }
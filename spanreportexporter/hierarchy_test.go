@@ -0,0 +1,83 @@
+package spanreportexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestCollectReport_HierarchyByNamespace(t *testing.T) {
+	exp := &spanReportExporter{logger: componenttest.NewNopTelemetrySettings().Logger, hierarchy: true}
+
+	// Two teams both have an "api" service
+	td := ptrace.NewTraces()
+	for _, r := range []struct {
+		namespace, service, env string
+		spans                   int
+	}{
+		{"shop", "api", "prod", 3},
+		{"shop", "api", "dev", 1},
+		{"shop", "worker", "prod", 2},
+		{"billing", "api", "prod", 4},
+		{"", "legacy", "prod", 1},
+	} {
+		rs := td.ResourceSpans().AppendEmpty()
+		if r.namespace != "" {
+			rs.Resource().Attributes().PutStr("service.namespace", r.namespace)
+		}
+		rs.Resource().Attributes().PutStr("service.name", r.service)
+		rs.Resource().Attributes().PutStr("deployment.environment.name", r.env)
+		spans := rs.ScopeSpans().AppendEmpty().Spans()
+		for range r.spans {
+			spans.AppendEmpty()
+		}
+	}
+	require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+
+	report := exp.collectReport(time.Now())
+	require.Len(t, report.Groups, 5)
+	type subtotal struct {
+		namespace, service string
+		total              uint64
+	}
+	var subtotals []subtotal
+	for _, s := range report.Subtotals {
+		assert.Equal(t, totalKey, s.Env)
+		subtotals = append(subtotals, subtotal{s.Namespace, s.Service, s.Hourly.Total})
+	}
+	assert.Equal(t, []subtotal{
+		{"billing", "*", 4},
+		{"billing", "api", 4},
+		{"shop", "*", 6},
+		{"shop", "api", 4},
+		{"shop", "worker", 2},
+		{"unknown", "*", 1},
+		{"unknown", "legacy", 1},
+	}, subtotals)
+
+	lines, err := formatTextLines(report)
+	require.NoError(t, err)
+	text := strings.Join(lines, "")
+	assert.Contains(t, text, "] namespace:billing, service:api, env:prod | Hourly(Total:4,")
+	assert.Contains(t, lines[7], "] namespace:shop, service:*, env:* | Hourly(Total:6,")
+
+	// The TUI tree shows every level, and hides the children of collapsed nodes
+	m := model{snapshot: snapshotResponse{Groups: exp.snapshot()}, collapsed: map[string]bool{}}
+	m.snapshot.Subtotals = subtotalsByNamespace(m.snapshot.Groups)
+	assert.Len(t, m.treeRows(), 12)
+	m.cursor = 3 // shop
+	m.toggle()
+	m.cursor = 5 // unknown/legacy
+	m.toggle()
+	var paths []string
+	for _, r := range m.treeRows() {
+		paths = append(paths, r.path)
+	}
+	assert.Equal(t, []string{"billing", "billing/api", "", "shop", "unknown", "unknown/legacy"}, paths)
+}
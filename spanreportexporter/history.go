@@ -0,0 +1,78 @@
+package spanreportexporter
+
+import (
+	"sync"
+	"time"
+)
+
+// historyPoint is the state of one group as of a report tick.
+type historyPoint struct {
+	Time    time.Time    `json:"time"`
+	Hourly  PeriodCounts `json:"hourly"`
+	Daily   PeriodCounts `json:"daily"`
+	Monthly PeriodCounts `json:"monthly"`
+}
+
+// historyStore keeps the reported values of every group in memory for a limited time,
+// so that they can be queried without an external database.
+type historyStore struct {
+	mu        sync.RWMutex
+	retention time.Duration
+	points    map[groupingKey][]historyPoint
+}
+
+func newHistoryStore(retention time.Duration) *historyStore {
+	return &historyStore{
+		retention: retention,
+		points:    make(map[groupingKey][]historyPoint),
+	}
+}
+
+// add records one report tick and drops points older than the retention period.
+func (h *historyStore) add(now time.Time, rows []GroupCounts) {
+	t := reportTime(now)
+	cutoff := t.Add(-h.retention)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range rows {
+		k := r.key()
+		h.points[k] = append(h.points[k], historyPoint{
+			Time:    t,
+			Hourly:  r.Hourly,
+			Daily:   r.Daily,
+			Monthly: r.Monthly,
+		})
+	}
+
+	for k, points := range h.points {
+		i := 0
+		for i < len(points) && points[i].Time.Before(cutoff) {
+			i++
+		}
+		if i == len(points) {
+			delete(h.points, k)
+		} else if i > 0 {
+			h.points[k] = append([]historyPoint(nil), points[i:]...)
+		}
+	}
+}
+
+// query returns the points of a group within [from, to]. A zero bound is treated as open.
+func (h *historyStore) query(k groupingKey, from, to time.Time) []historyPoint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := []historyPoint{}
+	for _, p := range h.points[k] {
+		if !from.IsZero() && p.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && p.Time.After(to) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
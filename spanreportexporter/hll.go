@@ -0,0 +1,57 @@
+package spanreportexporter
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits used to pick a register: 4096
+// registers (4KB per sketch) give a standard error of about 1.6%.
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog estimates the number of distinct 64-bit hashes added to it.
+type hyperLogLog struct {
+	registers [hllRegisters]uint8
+}
+
+func (h *hyperLogLog) add(hash uint64) {
+	idx := hash >> (64 - hllPrecision)
+	// The remaining bits, with a sentinel so that the rank is at most 64-p+1
+	w := hash<<hllPrecision | 1<<(hllPrecision-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// merge adds the hashes of o, as if they had been added to h.
+func (h *hyperLogLog) merge(o *hyperLogLog) {
+	for i, r := range o.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	const m = float64(hllRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(e))
+}
@@ -0,0 +1,284 @@
+package spanreportexporter
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// newAPIHandler returns the handler of the HTTP server.
+//
+//	GET /                                                 web dashboard
+//	GET /stream                                           server-sent events of the current counters
+//	GET /api/groups                                       list of service/env pairs
+//	GET /api/snapshot?service=&env=&sort=&order=          current counters of all groups
+//	GET /api/history?service=&env=&from=&to=              reported values of one group
+func (e *spanReportExporter) newAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", e.handleDashboard)
+	mux.HandleFunc("GET /stream", e.handleStream)
+	mux.HandleFunc("GET /api/groups", e.handleGroups)
+	mux.HandleFunc("GET /api/snapshot", e.handleSnapshot)
+	mux.HandleFunc("GET /api/history", e.handleHistory)
+	return mux
+}
+
+type groupName struct {
+	Tenant    string `json:"tenant,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Service   string `json:"service"`
+	Env       string `json:"env"`
+}
+
+type snapshotResponse struct {
+	Time         time.Time      `json:"time"`
+	StartTime    time.Time      `json:"start_time"`
+	WriteErrors  uint64         `json:"write_errors"`
+	PendingLines int            `json:"pending_lines"`
+	Signals      []string       `json:"signals"`
+	Groups       []GroupCounts  `json:"groups"`
+	Total        GroupCounts    `json:"total"`
+	EnvTotals    []GroupCounts  `json:"env_totals,omitempty"`
+	Subtotals    []GroupCounts  `json:"subtotals,omitempty"`
+	TenantTotals []GroupCounts  `json:"tenant_totals,omitempty"`
+	Teams        []TeamCounts   `json:"teams,omitempty"`
+	Senders      *SenderPeriods `json:"senders,omitempty"`
+}
+
+type historyResponse struct {
+	Service string         `json:"service"`
+	Env     string         `json:"env"`
+	Points  []historyPoint `json:"points"`
+}
+
+func (e *spanReportExporter) handleDashboard(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// handleStream pushes a snapshot of all groups, with the per-group deltas since
+// the previous event, as a server-sent event every second.
+func (e *spanReportExporter) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := e.stream.subscribe()
+	defer e.stream.unsubscribe(ch)
+
+	// Send the current state right away instead of waiting for the next tick
+	data, err := json.Marshal(streamEvent{Time: time.Now(), Groups: e.snapshot(), Deltas: []groupDelta{}})
+	if err != nil {
+		e.logger.Error("Failed to encode snapshot", zap.Error(err))
+		return
+	}
+	for {
+		if _, err := fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case data, ok = <-ch:
+			if !ok {
+				// Dropped for being too slow
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+func (e *spanReportExporter) handleGroups(w http.ResponseWriter, r *http.Request) {
+	rows, err := filterSnapshot(e.snapshot(), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	names := []groupName{}
+	for _, row := range rows {
+		names = append(names, groupName{Tenant: row.Tenant, Namespace: row.Namespace, Service: row.Service, Env: row.Env})
+	}
+	e.writeJSON(w, names)
+}
+
+func (e *spanReportExporter) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	rows, err := filterSnapshot(e.snapshot(), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sortSnapshot(rows, r.URL.Query().Get("sort"), r.URL.Query().Get("order")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snap, _ := e.localSnapshot()
+	// The totals cover the groups that match the filter
+	snap.Groups = rows
+	snap.Total, snap.EnvTotals = summarize(rows, e.envSubtotals)
+	snap.Subtotals = subtotalsByNamespace(rows)
+	snap.TenantTotals = totalsByTenant(rows)
+	snap.Teams = rollUpTeams(rows)
+	e.writeJSON(w, snap)
+}
+
+func (e *spanReportExporter) handleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	service, env := q.Get("service"), q.Get("env")
+	if service == "" || env == "" {
+		http.Error(w, "both service and env are required", http.StatusBadRequest)
+		return
+	}
+	from, err := parseTimeParam(q.Get("from"))
+	if err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(q.Get("to"))
+	if err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e.writeJSON(w, historyResponse{
+		Service: service,
+		Env:     env,
+		Points:  e.history.query(groupingKey{tenant: q.Get("tenant"), namespace: q.Get("namespace"), service: service, env: env}, from, to),
+	})
+}
+
+func (e *spanReportExporter) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		e.logger.Debug("Failed to write HTTP response", zap.Error(err))
+	}
+}
+
+// localSnapshot is the snapshotFunc of the TUI running inside the collector.
+func (e *spanReportExporter) localSnapshot() (snapshotResponse, error) {
+	snap := snapshotResponse{
+		Time:        time.Now(),
+		StartTime:   e.startTime,
+		WriteErrors: e.writeErrors.Load(),
+		Signals:     e.signals,
+		Groups:      e.snapshot(),
+	}
+	snap.Total, snap.EnvTotals = summarize(snap.Groups, e.envSubtotals)
+	snap.Subtotals = subtotalsByNamespace(snap.Groups)
+	snap.TenantTotals = totalsByTenant(snap.Groups)
+	snap.Teams = rollUpTeams(snap.Groups)
+	snap.Senders = e.senders.periods(nil)
+	for _, s := range e.sinks {
+		if ps, ok := s.ReportSink.(pendingReporter); ok {
+			pending, _ := ps.status()
+			snap.PendingLines += pending
+		}
+	}
+	return snap, nil
+}
+
+// snapshot returns the current counters of all groups, sorted like getSortedEntries.
+func (e *spanReportExporter) snapshot() []GroupCounts {
+	rows := []GroupCounts{}
+	for _, entry := range e.getSortedEntries() {
+		row := entry.stats.snapshot(entry.key)
+		if e.anomalies != nil {
+			row.Anomaly = e.anomalies.anomaly(entry.key)
+		}
+		rows = append(rows, row)
+	}
+	e.setOwners(rows)
+	return rows
+}
+
+// filterSnapshot keeps the rows whose service and env match the "service" and "env" glob parameters.
+func filterSnapshot(rows []GroupCounts, r *http.Request) ([]GroupCounts, error) {
+	servicePattern := r.URL.Query().Get("service")
+	envPattern := r.URL.Query().Get("env")
+	if servicePattern == "" && envPattern == "" {
+		return rows, nil
+	}
+
+	filtered := []GroupCounts{}
+	for _, row := range rows {
+		ok, err := matchGlob(servicePattern, row.Service)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service pattern: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		ok, err = matchGlob(envPattern, row.Env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid env pattern: %w", err)
+		}
+		if ok {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered, nil
+}
+
+// matchGlob reports whether name matches the shell pattern. An empty pattern matches everything.
+func matchGlob(pattern, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+	return path.Match(pattern, name)
+}
+
+// sortSnapshot sorts rows by "service", "env" or the total of "hourly", "daily" or "monthly".
+// Rows are already sorted by service and env, so the sort is stable.
+func sortSnapshot(rows []GroupCounts, field, order string) error {
+	var less func(a, b GroupCounts) bool
+	switch field {
+	case "", "service":
+		less = func(a, b GroupCounts) bool { return a.Service < b.Service }
+	case "env":
+		less = func(a, b GroupCounts) bool { return a.Env < b.Env }
+	case "hourly":
+		less = func(a, b GroupCounts) bool { return a.Hourly.Total < b.Hourly.Total }
+	case "daily":
+		less = func(a, b GroupCounts) bool { return a.Daily.Total < b.Daily.Total }
+	case "monthly":
+		less = func(a, b GroupCounts) bool { return a.Monthly.Total < b.Monthly.Total }
+	default:
+		return fmt.Errorf("unknown sort field %q", field)
+	}
+
+	switch strings.ToLower(order) {
+	case "", "asc":
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	case "desc":
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[j], rows[i]) })
+	default:
+		return fmt.Errorf("unknown sort order %q", order)
+	}
+	return nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps. An empty value returns the zero time.
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,176 @@
+package spanreportexporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func newAPITestExporter() *spanReportExporter {
+	exp := &spanReportExporter{
+		logger:  componenttest.NewNopTelemetrySettings().Logger,
+		history: newHistoryStore(24 * time.Hour),
+	}
+	for _, g := range []struct {
+		service, env string
+		hourly       uint64
+	}{
+		{"order-api", "prod", 30},
+		{"order-api", "dev", 5},
+		{"auth-svc", "prod", 10},
+	} {
+		s := &spanStats{}
+		s.hourly.Store(g.hourly)
+		exp.statsMap.Store(groupingKey{service: g.service, env: g.env}, s)
+	}
+	return exp
+}
+
+func getJSON(t *testing.T, h http.Handler, url string, v any) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec.Code
+}
+
+func TestAPI_SnapshotFilterAndSort(t *testing.T) {
+	h := newAPITestExporter().newAPIHandler()
+
+	// Default order: service -> env
+	var all snapshotResponse
+	require.Equal(t, http.StatusOK, getJSON(t, h, "/api/snapshot", &all))
+	require.Len(t, all.Groups, 3)
+	assert.Equal(t, "auth-svc", all.Groups[0].Service)
+	assert.Equal(t, "dev", all.Groups[1].Env)
+
+	// Glob filter and sort by hourly total
+	var prod snapshotResponse
+	require.Equal(t, http.StatusOK, getJSON(t, h, "/api/snapshot?env=pr*&sort=hourly&order=desc", &prod))
+	require.Len(t, prod.Groups, 2)
+	assert.Equal(t, "order-api", prod.Groups[0].Service)
+	assert.Equal(t, uint64(30), prod.Groups[0].Hourly.Total)
+
+	var names []groupName
+	require.Equal(t, http.StatusOK, getJSON(t, h, "/api/groups?service=order-*", &names))
+	assert.Equal(t, []groupName{{Service: "order-api", Env: "dev"}, {Service: "order-api", Env: "prod"}}, names)
+
+	// Invalid parameters
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, h, "/api/snapshot?sort=size", nil))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, h, "/api/snapshot?service=[", nil))
+}
+
+func TestAPI_History(t *testing.T) {
+	exp := newAPITestExporter()
+	h := exp.newAPIHandler()
+
+	base := time.Date(2025, 12, 18, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		exp.history.add(base.Add(time.Duration(i)*time.Hour), exp.snapshot())
+	}
+
+	var res historyResponse
+	require.Equal(t, http.StatusOK, getJSON(t, h, "/api/history?service=order-api&env=prod&from=2025-12-18T10:00:00Z", &res))
+	require.Len(t, res.Points, 2)
+	assert.Equal(t, base.Add(time.Hour-time.Second), res.Points[0].Time.UTC())
+	assert.Equal(t, uint64(30), res.Points[0].Hourly.Total)
+
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, h, "/api/history?service=order-api", nil))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, h, "/api/history?service=order-api&env=prod&to=yesterday", nil))
+
+	// Points older than the retention period are dropped
+	exp.history.add(base.Add(48*time.Hour), exp.snapshot())
+	require.Equal(t, http.StatusOK, getJSON(t, h, "/api/history?service=order-api&env=prod", &res))
+	assert.Len(t, res.Points, 1)
+}
+
+func TestAPI_DashboardAndStream(t *testing.T) {
+	exp := newAPITestExporter()
+	exp.stopCh = make(chan struct{})
+	exp.stream = newStreamHub()
+	srv := httptest.NewServer(exp.newAPIHandler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/html")
+
+	res, err = http.Get(srv.URL + "/stream")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// The first event is sent immediately
+	reader := bufio.NewReader(res.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: snapshot\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	var ev streamEvent
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev))
+	assert.Len(t, ev.Groups, 3)
+	assert.Empty(t, ev.Deltas)
+
+	// Stopping the exporter ends the stream
+	close(exp.stopCh)
+}
+
+func TestStreamHub_Deltas(t *testing.T) {
+	h := newStreamHub()
+	now := time.Now()
+	rows := func(svcA, svcB uint64) []GroupCounts {
+		r := []GroupCounts{{Service: "a", Env: "prod", Hourly: PeriodCounts{Total: svcA, Internal: svcA}}}
+		if svcB > 0 {
+			r = append(r, GroupCounts{Service: "b", Env: "prod", Hourly: PeriodCounts{Total: svcB, HTTP: 1, Server: 1}})
+		}
+		return r
+	}
+
+	// The first event has nothing to compare with
+	assert.Empty(t, h.nextEvent(now, rows(10, 0)).Deltas)
+
+	// Increase of an existing group, and a new group
+	ev := h.nextEvent(now, rows(15, 3))
+	assert.Equal(t, []groupDelta{
+		{Service: "a", Env: "prod", Delta: PeriodCounts{Total: 5, Internal: 5}},
+		{Service: "b", Env: "prod", Delta: PeriodCounts{Total: 3, HTTP: 1, Server: 1}},
+	}, ev.Deltas)
+
+	// After an hourly reset, everything counted since is new; unchanged groups are omitted
+	ev = h.nextEvent(now, rows(2, 3))
+	assert.Equal(t, []groupDelta{{Service: "a", Env: "prod", Delta: PeriodCounts{Total: 2, Internal: 2}}}, ev.Deltas)
+}
+
+func TestStreamHub_SlowClientIsDropped(t *testing.T) {
+	h := newStreamHub()
+	slow := h.subscribe()
+	fast := h.subscribe()
+
+	for i := 0; i < streamBufferSize+1; i++ {
+		h.broadcast([]byte("event"))
+		<-fast
+	}
+
+	// broadcast never blocked; the slow client has been dropped and its channel closed
+	n := 0
+	for range slow {
+		n++
+	}
+	assert.Equal(t, streamBufferSize, n)
+	assert.True(t, h.hasClients())
+	h.unsubscribe(slow) // no-op for a dropped client
+	h.unsubscribe(fast)
+	assert.False(t, h.hasClients())
+}
@@ -0,0 +1,74 @@
+package spanreportexporter
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// Signals an exporter can receive, listed in Report.Signals.
+const (
+	signalTraces = "traces"
+	signalLogs   = "logs"
+)
+
+// hasSignal reports whether the exporter is used in a pipeline of the signal.
+func (e *spanReportExporter) hasSignal(signal string) bool {
+	return slices.Contains(e.signals, signal)
+}
+
+// errorSeverityTexts are the severity texts counted as ERROR+ when a log
+// record has no severity number.
+var errorSeverityTexts = []string{"ERROR", "FATAL", "CRITICAL", "ALERT", "EMERGENCY", "PANIC"}
+
+// isErrorLog reports whether a log record has a severity of ERROR or higher.
+func isErrorLog(lr plog.LogRecord) bool {
+	if n := lr.SeverityNumber(); n != plog.SeverityNumberUnspecified {
+		return n >= plog.SeverityNumberError
+	}
+	return slices.Contains(errorSeverityTexts, strings.ToUpper(lr.SeverityText()))
+}
+
+// ConsumeLogs counts log records per service and environment, with the same
+// hourly/daily/monthly semantics as spans.
+func (e *spanReportExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	e.senders.add(ctx, SenderCounts{Logs: uint64(ld.LogRecordCount())})
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		key := e.groupKey(ctx, rl.Resource().Attributes())
+
+		stats := e.statsFor(key)
+
+		var count, errors uint64
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				count++
+				if isErrorLog(records.At(k)) {
+					errors++
+				}
+			}
+		}
+
+		stats.logHourly.Add(count)
+		stats.logDaily.Add(count)
+		stats.logMonthly.Add(count)
+		stats.logErrorHourly.Add(errors)
+		stats.logErrorDaily.Add(errors)
+		stats.logErrorMonthly.Add(errors)
+
+		if e.verbose {
+			e.logger.Info("Processed log records",
+				zap.String("service", key.service),
+				zap.String("environment", key.env),
+				zap.Uint64("log_count", count),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package spanreportexporter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestConsumeLogs_CountsBySeverity(t *testing.T) {
+	exp := &spanReportExporter{logger: componenttest.NewNopTelemetrySettings().Logger}
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "order-api")
+	rl.Resource().Attributes().PutStr("deployment.environment.name", "prod")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberInfo)
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberWarn4)
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberError)
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberFatal2)
+	records.AppendEmpty().SetSeverityText("critical") // No severity number: the text decides
+	records.AppendEmpty()
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), ld))
+
+	val, ok := exp.statsMap.Load(groupingKey{service: "order-api", env: "prod"})
+	require.True(t, ok)
+	row := val.(*spanStats).snapshot(groupingKey{})
+	for _, c := range []PeriodCounts{row.Hourly, row.Daily, row.Monthly} {
+		assert.Equal(t, uint64(6), c.Logs)
+		assert.Equal(t, uint64(3), c.LogErrors)
+		assert.Equal(t, uint64(0), c.Total, "log records are not spans")
+	}
+}
+
+func TestFactory_SharesExporterAcrossSignals(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.TUI = false
+	cfg.Sinks = []SinkConfig{{Type: sinkTypeFile, Path: filepath.Join(t.TempDir(), "report.txt")}}
+	set := exporter.Settings{
+		ID:                component.NewID(componentType),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+		BuildInfo:         component.NewDefaultBuildInfo(),
+	}
+
+	ctx := context.Background()
+	traces, err := factory.CreateTraces(ctx, set, cfg)
+	require.NoError(t, err)
+	logs, err := factory.CreateLogs(ctx, set, cfg)
+	require.NoError(t, err)
+
+	shared := sharedExporters[cfg]
+	require.NotNil(t, shared)
+	assert.Equal(t, []string{signalTraces, signalLogs}, shared.exp.signals)
+
+	// The exporter is started once, and stopped by the last pipeline only
+	host := componenttest.NewNopHost()
+	require.NoError(t, traces.Start(ctx, host))
+	require.NoError(t, logs.Start(ctx, host))
+	require.NoError(t, traces.Shutdown(ctx))
+	select {
+	case <-shared.exp.stopCh:
+		t.Fatal("exporter stopped while the logs pipeline is running")
+	default:
+	}
+	require.NoError(t, logs.Shutdown(ctx))
+	<-shared.exp.stopCh
+	assert.NotContains(t, sharedExporters, cfg)
+}
@@ -0,0 +1,146 @@
+package spanreportexporter
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const signalMetrics = "metrics"
+
+// ConsumeMetrics counts metric data points per service and environment, by
+// metric type, and the distinct series they belong to. A series is identified
+// by the resource attributes, the metric name and the data point attributes.
+func (e *spanReportExporter) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	e.senders.add(ctx, SenderCounts{DataPoints: uint64(md.DataPointCount())})
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		attrs := rm.Resource().Attributes()
+		key := e.groupKey(ctx, attrs)
+
+		stats := e.statsFor(key)
+
+		var d xxhash.Digest
+		d.Reset()
+		hashAttributes(&d, attrs)
+		resourceHash := d.Sum64()
+
+		var counts metricCounts
+		var hashes []uint64
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				m := metrics.At(k)
+				forEachDataPoint(m, func(dpAttrs pcommon.Map) {
+					counts.add(m.Type())
+					d.Reset()
+					var buf [8]byte
+					binary.LittleEndian.PutUint64(buf[:], resourceHash)
+					d.Write(buf[:])
+					d.WriteString(m.Name())
+					d.Write([]byte{0})
+					hashAttributes(&d, dpAttrs)
+					hashes = append(hashes, d.Sum64())
+				})
+			}
+		}
+
+		for _, p := range allPeriods {
+			ms := &stats.metrics[p]
+			ms.dataPoints.Add(counts.dataPoints)
+			ms.gauge.Add(counts.gauge)
+			ms.sum.Add(counts.sum)
+			ms.histogram.Add(counts.histogram)
+			ms.expHistogram.Add(counts.expHistogram)
+			ms.summary.Add(counts.summary)
+		}
+		if len(hashes) > 0 {
+			stats.addSeries(hashes)
+		}
+
+		if e.verbose {
+			e.logger.Info("Processed data points",
+				zap.String("service", key.service),
+				zap.String("environment", key.env),
+				zap.Uint64("data_point_count", counts.dataPoints),
+			)
+		}
+	}
+	return nil
+}
+
+// metricCounts counts the data points of one request before they are added to the atomics.
+type metricCounts struct {
+	dataPoints, gauge, sum, histogram, expHistogram, summary uint64
+}
+
+func (c *metricCounts) add(t pmetric.MetricType) {
+	c.dataPoints++
+	switch t {
+	case pmetric.MetricTypeGauge:
+		c.gauge++
+	case pmetric.MetricTypeSum:
+		c.sum++
+	case pmetric.MetricTypeHistogram:
+		c.histogram++
+	case pmetric.MetricTypeExponentialHistogram:
+		c.expHistogram++
+	case pmetric.MetricTypeSummary:
+		c.summary++
+	}
+}
+
+// forEachDataPoint calls f with the attributes of every data point of m.
+func forEachDataPoint(m pmetric.Metric, f func(pcommon.Map)) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := m.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		dps := m.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := m.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		dps := m.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).Attributes())
+		}
+	}
+}
+
+// hashAttributes writes the attributes to d in key order, so that the hash
+// does not depend on the order in which they were set.
+func hashAttributes(d *xxhash.Digest, attrs pcommon.Map) {
+	keys := make([]string, 0, attrs.Len())
+	for k := range attrs.All() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, _ := attrs.Get(k)
+		d.WriteString(k)
+		d.Write([]byte{0})
+		d.WriteString(v.AsString())
+		d.Write([]byte{0})
+	}
+}
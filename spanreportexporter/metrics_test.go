@@ -0,0 +1,69 @@
+package spanreportexporter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConsumeMetrics_CountsDataPointsAndSeries(t *testing.T) {
+	exp := &spanReportExporter{logger: componenttest.NewNopTelemetrySettings().Logger}
+
+	newMetrics := func(pods int) pmetric.Metrics {
+		md := pmetric.NewMetrics()
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", "order-api")
+		rm.Resource().Attributes().PutStr("deployment.environment.name", "prod")
+		ms := rm.ScopeMetrics().AppendEmpty().Metrics()
+		gauge := ms.AppendEmpty()
+		gauge.SetName("memory")
+		gauge.SetEmptyGauge()
+		sum := ms.AppendEmpty()
+		sum.SetName("requests")
+		sum.SetEmptySum()
+		for i := 0; i < pods; i++ {
+			gauge.Gauge().DataPoints().AppendEmpty().Attributes().PutStr("pod", fmt.Sprintf("pod-%d", i))
+			sum.Sum().DataPoints().AppendEmpty().Attributes().PutStr("pod", fmt.Sprintf("pod-%d", i))
+		}
+		hist := ms.AppendEmpty()
+		hist.SetName("latency")
+		hist.SetEmptyHistogram().DataPoints().AppendEmpty()
+		return md
+	}
+
+	// The same 201 series are sent twice
+	ctx := context.Background()
+	require.NoError(t, exp.ConsumeMetrics(ctx, newMetrics(100)))
+	require.NoError(t, exp.ConsumeMetrics(ctx, newMetrics(100)))
+
+	val, ok := exp.statsMap.Load(groupingKey{service: "order-api", env: "prod"})
+	require.True(t, ok)
+	stats := val.(*spanStats)
+	c := stats.load(periodDaily)
+	assert.Equal(t, uint64(402), c.DataPoints)
+	assert.Equal(t, uint64(200), c.Gauge)
+	assert.Equal(t, uint64(200), c.Sum)
+	assert.Equal(t, uint64(2), c.Histogram)
+	assert.InDelta(t, 201, c.Series, 5)
+
+	// Taking a period starts a new series count
+	assert.InDelta(t, 201, stats.take(periodHourly).Series, 5)
+	assert.Equal(t, uint64(0), stats.load(periodHourly).Series)
+	assert.InDelta(t, 201, stats.load(periodMonthly).Series, 5)
+}
+
+func TestHyperLogLog_Estimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		var h hyperLogLog
+		for i := 0; i < n; i++ {
+			h.add(xxhash.Sum64String(fmt.Sprintf("series-%d", i)))
+		}
+		assert.InEpsilon(t, n, h.estimate(), 0.05, "n=%d", n)
+	}
+}
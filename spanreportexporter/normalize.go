@@ -0,0 +1,115 @@
+package spanreportexporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NormalizeConfig rewrites the service names and environments before they are
+// grouped, so that variants of a name are counted as one group.
+type NormalizeConfig struct {
+	Service NormalizeRules `mapstructure:"service"`
+	Env     NormalizeRules `mapstructure:"env"`
+}
+
+// NormalizeRules are applied in order: trim, lowercase, rewrite, then aliases.
+type NormalizeRules struct {
+	Lowercase bool          `mapstructure:"lowercase"`
+	Trim      bool          `mapstructure:"trim"`
+	Rewrite   []RewriteRule `mapstructure:"rewrite"`
+	// Aliases maps a name, after the other rules, to the name it is counted as
+	Aliases map[string]string `mapstructure:"aliases"`
+}
+
+// RewriteRule replaces the matches of a regular expression, with $1 etc. for the submatches.
+type RewriteRule struct {
+	Pattern     string `mapstructure:"pattern"`
+	Replacement string `mapstructure:"replacement"`
+}
+
+func (c NormalizeConfig) validate() error {
+	if _, err := c.Service.compile(); err != nil {
+		return fmt.Errorf("normalize.service: %w", err)
+	}
+	if _, err := c.Env.compile(); err != nil {
+		return fmt.Errorf("normalize.env: %w", err)
+	}
+	return nil
+}
+
+func (r NormalizeRules) enabled() bool {
+	return r.Lowercase || r.Trim || len(r.Rewrite) > 0 || len(r.Aliases) > 0
+}
+
+// nameRules are the compiled NormalizeRules of one attribute.
+type nameRules struct {
+	NormalizeRules
+	patterns []*regexp.Regexp
+}
+
+func (r NormalizeRules) compile() (*nameRules, error) {
+	if !r.enabled() {
+		return nil, nil
+	}
+	compiled := &nameRules{NormalizeRules: r}
+	for i, rule := range r.Rewrite {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite[%d]: %w", i, err)
+		}
+		compiled.patterns = append(compiled.patterns, re)
+	}
+	return compiled, nil
+}
+
+func (r *nameRules) apply(name string) string {
+	if r == nil {
+		return name
+	}
+	if r.Trim {
+		name = strings.TrimSpace(name)
+	}
+	if r.Lowercase {
+		name = strings.ToLower(name)
+	}
+	for i, re := range r.patterns {
+		name = re.ReplaceAllString(name, r.Rewrite[i].Replacement)
+	}
+	if alias, ok := r.Aliases[name]; ok {
+		name = alias
+	}
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// normalizer applies the normalize rules to grouping keys. A nil normalizer
+// keeps the keys as they are.
+type normalizer struct {
+	service *nameRules
+	env     *nameRules
+}
+
+func newNormalizer(c NormalizeConfig) (*normalizer, error) {
+	service, err := c.Service.compile()
+	if err != nil {
+		return nil, err
+	}
+	env, err := c.Env.compile()
+	if err != nil {
+		return nil, err
+	}
+	if service == nil && env == nil {
+		return nil, nil
+	}
+	return &normalizer{service: service, env: env}, nil
+}
+
+func (n *normalizer) apply(k groupingKey) groupingKey {
+	if n == nil {
+		return k
+	}
+	return groupingKey{service: n.service.apply(k.service), env: n.env.apply(k.env)}
+}
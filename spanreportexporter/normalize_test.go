@@ -0,0 +1,50 @@
+package spanreportexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestConsumeTraces_NormalizesServiceNames(t *testing.T) {
+	n, err := newNormalizer(NormalizeConfig{
+		Service: NormalizeRules{
+			Lowercase: true,
+			Trim:      true,
+			Rewrite:   []RewriteRule{{Pattern: `-canary$`, Replacement: ""}},
+			Aliases:   map[string]string{"orders": "order-api"},
+		},
+		Env: NormalizeRules{Aliases: map[string]string{"production": "prod"}},
+	})
+	require.NoError(t, err)
+	exp := &spanReportExporter{logger: componenttest.NewNopTelemetrySettings().Logger, normalizer: n}
+
+	td := ptrace.NewTraces()
+	for _, name := range []string{"order-api", "Order-API ", "order-api-canary", "orders"} {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", name)
+		rs.Resource().Attributes().PutStr("deployment.environment.name", "production")
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	}
+	require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+
+	entries := exp.getSortedEntries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, groupingKey{service: "order-api", env: "prod"}, entries[0].key)
+	assert.Equal(t, uint64(4), entries[0].stats.load(periodHourly).Total)
+}
+
+func TestNormalizeConfig_Validate(t *testing.T) {
+	assert.NoError(t, NormalizeConfig{}.validate())
+	err := NormalizeConfig{Env: NormalizeRules{Rewrite: []RewriteRule{{Pattern: "("}}}}.validate()
+	assert.ErrorContains(t, err, "normalize.env: rewrite[0]")
+
+	// A name rewritten to nothing is counted as unknown
+	n, err := newNormalizer(NormalizeConfig{Service: NormalizeRules{Rewrite: []RewriteRule{{Pattern: ".*"}}}})
+	require.NoError(t, err)
+	assert.Equal(t, groupingKey{service: "unknown", env: "dev"}, n.apply(groupingKey{service: "x", env: "dev"}))
+}
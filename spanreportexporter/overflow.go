@@ -0,0 +1,154 @@
+package spanreportexporter
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// overflowService is the service and env of the group counting the resources
+// that arrived after max_groups was reached, and the tenant counting those of
+// the tenants beyond max_tenants.
+const overflowService = "__overflow__"
+
+// overflowKey returns the key of the __overflow__ group of a tenant, which
+// also has a namespace with hierarchy.
+func (e *spanReportExporter) overflowKey(tenant string) groupingKey {
+	k := groupingKey{tenant: tenant, service: overflowService, env: overflowService}
+	if e.hierarchy {
+		k.namespace = overflowService
+	}
+	return k
+}
+
+// isOverflow reports whether the group counts resources beyond max_groups or max_tenants.
+func (g GroupCounts) isOverflow() bool {
+	return g.Service == overflowService || g.Tenant == overflowService
+}
+
+// groupCounter returns the number of groups of a tenant other than
+// __overflow__, when maxGroups is set; each tenant has its own max_groups.
+func (e *spanReportExporter) groupCounter(tenant string) *atomic.Int64 {
+	val, _ := e.groupCounts.LoadOrStore(tenant, &atomic.Int64{})
+	return val.(*atomic.Int64)
+}
+
+// maxOverflowSamples is the number of offending groups listed in the warnings.
+const maxOverflowSamples = 5
+
+// overflowTracker remembers a few of the groups counted under __overflow__, to
+// tell which attribute went wrong.
+type overflowTracker struct {
+	mu        sync.Mutex
+	samples   []string
+	resources uint64
+	warned    bool
+}
+
+// add records a resource of group k counted under __overflow__. It returns true
+// the first time, so that the limit is reported as soon as it is reached.
+func (t *overflowTracker) add(k groupingKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resources++
+	s := k.label()
+	if len(t.samples) < maxOverflowSamples && !slices.Contains(t.samples, s) {
+		t.samples = append(t.samples, s)
+	}
+	first := !t.warned
+	t.warned = true
+	return first
+}
+
+// take returns the samples and the number of resources since the last call.
+func (t *overflowTracker) take() ([]string, uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	samples, n := t.samples, t.resources
+	t.samples, t.resources = nil, 0
+	return samples, n
+}
+
+// statsFor returns the counters of group k to receive new data, creating them
+// for a new group. Once max_groups groups exist (per tenant), new groups are
+// counted under __overflow__ instead, and so are new tenants once max_tenants
+// tenants exist.
+func (e *spanReportExporter) statsFor(k groupingKey) *spanStats {
+	if val, ok := e.statsMap.Load(k); ok {
+		s := val.(*spanStats)
+		s.touch(time.Now())
+		return s
+	}
+	tenants := e.maxTenants > 0 && k.tenant != ""
+	if tenants {
+		if tenant := e.reserveTenant(k.tenant); tenant != k.tenant {
+			if e.overflow.add(k) {
+				e.logger.Warn("Too many tenants, counting new ones under "+overflowService,
+					zap.Int("max_tenants", e.maxTenants),
+					zap.String("tenant", k.tenant),
+				)
+			}
+			k.tenant = tenant
+			if val, ok := e.statsMap.Load(k); ok {
+				e.releaseTenant(k.tenant)
+				s := val.(*spanStats)
+				s.touch(time.Now())
+				return s
+			}
+		}
+	}
+	overflowKey := e.overflowKey(k.tenant)
+	if e.maxGroups > 0 && k != overflowKey {
+		count := e.groupCounter(k.tenant)
+		if count.Add(1) > int64(e.maxGroups) {
+			count.Add(-1)
+			if e.overflow.add(k) {
+				e.logger.Warn("Too many groups, counting new ones under "+overflowService,
+					zap.Int("max_groups", e.maxGroups),
+					zap.String("tenant", k.tenant),
+					zap.String("service", k.service),
+					zap.String("environment", k.env),
+				)
+			}
+			k = overflowKey
+		}
+	}
+	val, loaded := e.statsMap.LoadOrStore(k, &spanStats{})
+	if loaded && e.maxGroups > 0 && k != overflowKey {
+		// Created concurrently by another request
+		e.groupCounter(k.tenant).Add(-1)
+	}
+	if loaded && tenants {
+		e.releaseTenant(k.tenant)
+	}
+	s := val.(*spanStats)
+	s.touch(time.Now())
+	return s
+}
+
+// forgetGroup is called when group k has been removed from statsMap.
+func (e *spanReportExporter) forgetGroup(k groupingKey) {
+	if e.maxGroups > 0 && k != e.overflowKey(k.tenant) {
+		e.groupCounter(k.tenant).Add(-1)
+	}
+	if e.maxTenants > 0 && k.tenant != "" {
+		e.releaseTenant(k.tenant)
+	}
+}
+
+// warnOverflow logs the groups counted under __overflow__ since the last report.
+func (e *spanReportExporter) warnOverflow() {
+	samples, n := e.overflow.take()
+	if n == 0 {
+		return
+	}
+	e.logger.Warn("Resources counted under "+overflowService+" since the last report; check the service.name and deployment.environment (and tenant) of your senders",
+		zap.Int("max_groups", e.maxGroups),
+		zap.Int("max_tenants", e.maxTenants),
+		zap.Uint64("resources", n),
+		zap.Strings("samples", samples),
+	)
+}
@@ -0,0 +1,51 @@
+package spanreportexporter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestConsumeTraces_OverflowsAfterMaxGroups(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	exp := &spanReportExporter{logger: zap.New(core), maxGroups: 2}
+
+	// A buggy deploy uses the pod UID as service name
+	td := ptrace.NewTraces()
+	for i := range 5 {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", fmt.Sprintf("pod-%d", i))
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	}
+	require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+
+	groups := map[string]uint64{}
+	for _, entry := range exp.getSortedEntries() {
+		groups[entry.key.service] = entry.stats.load(periodHourly).Total
+	}
+	assert.Equal(t, map[string]uint64{"pod-0": 1, "pod-1": 1, overflowService: 3}, groups)
+	assert.Equal(t, 1, logs.FilterMessageSnippet("Too many groups").Len(), "the limit is reported once")
+
+	// Existing groups keep being counted, and removing one makes room for a new one
+	require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+	require.NoError(t, exp.resetGroup(groupingKey{service: "pod-1", env: "unknown"}, nil))
+	require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+	val, ok := exp.statsMap.Load(groupingKey{service: "pod-1", env: "unknown"})
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), val.(*spanStats).load(periodHourly).Total)
+	_, ok = exp.statsMap.Load(groupingKey{service: "pod-2", env: "unknown"})
+	assert.False(t, ok)
+
+	// Each report lists a few of the offending groups
+	exp.warnOverflow()
+	warnings := logs.FilterMessageSnippet("since the last report").All()
+	require.Len(t, warnings, 1)
+	samples := warnings[0].ContextMap()["samples"]
+	assert.Equal(t, []any{"service:pod-2, env:unknown", "service:pod-3, env:unknown", "service:pod-4, env:unknown"}, samples)
+}
@@ -0,0 +1,258 @@
+package spanreportexporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+// OwnershipConfig points to a YAML or CSV file mapping service/env patterns to
+// the team and cost center that own them. The file is reloaded when it changes.
+type OwnershipConfig struct {
+	Path           string `mapstructure:"path"`
+	ReloadInterval string `mapstructure:"reload_interval"`
+}
+
+func (c OwnershipConfig) validate() error {
+	if c.ReloadInterval != "" {
+		if d, err := time.ParseDuration(c.ReloadInterval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid ownership.reload_interval %q", c.ReloadInterval)
+		}
+	}
+	return nil
+}
+
+// unownedTeam is the team of the groups that match no rule.
+const unownedTeam = "unowned"
+
+// ownerRule assigns the groups matching Service and Env (glob patterns, * when
+// empty) to a team. The first matching rule of the file wins.
+type ownerRule struct {
+	Service    string `yaml:"service"`
+	Env        string `yaml:"env"`
+	Team       string `yaml:"team"`
+	CostCenter string `yaml:"cost_center"`
+}
+
+type ownership struct {
+	rules []ownerRule
+}
+
+// parseOwnership reads the rules of an ownership file, in CSV if its name ends
+// with .csv and in YAML otherwise.
+func parseOwnership(name string, data []byte) (*ownership, error) {
+	var rules []ownerRule
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		rules, err = parseOwnershipCSV(data)
+	} else {
+		var doc struct {
+			Owners []ownerRule `yaml:"owners"`
+		}
+		err = yaml.Unmarshal(data, &doc)
+		rules = doc.Owners
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range rules {
+		if r.Team == "" {
+			return nil, fmt.Errorf("rule %d: team is required", i+1)
+		}
+		for _, pattern := range []string{r.Service, r.Env} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid pattern %q: %w", i+1, pattern, err)
+			}
+		}
+	}
+	return &ownership{rules: rules}, nil
+}
+
+// parseOwnershipCSV reads a CSV file whose header names the service, env, team
+// and cost_center columns.
+func parseOwnershipCSV(data []byte) ([]ownerRule, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+	column := func(name string) int {
+		return slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), name) })
+	}
+	service, env, team, costCenter := column("service"), column("env"), column("team"), column("cost_center")
+	if team < 0 {
+		return nil, errors.New("the header has no team column")
+	}
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rules []ownerRule
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rules, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, ownerRule{
+			Service:    field(record, service),
+			Env:        field(record, env),
+			Team:       field(record, team),
+			CostCenter: field(record, costCenter),
+		})
+	}
+}
+
+// owner returns the team and cost center of group k.
+func (o *ownership) owner(k groupingKey) (string, string) {
+	match := func(pattern, name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return pattern == "" || ok
+	}
+	if o == nil {
+		return unownedTeam, ""
+	}
+	for _, r := range o.rules {
+		if match(r.Service, k.service) && match(r.Env, k.env) {
+			return r.Team, r.CostCenter
+		}
+	}
+	return unownedTeam, ""
+}
+
+// ownershipWatcher holds the rules of the ownership file, reloaded when its
+// modification time or size changes.
+type ownershipWatcher struct {
+	path    string
+	current atomic.Pointer[ownership]
+	modTime time.Time
+	size    int64
+}
+
+// reload reads the file if it changed since the last attempt. On error, the
+// previous rules are kept.
+func (w *ownershipWatcher) reload() (bool, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if w.current.Load() != nil && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return false, err
+	}
+	// An invalid file is reported once, not at every poll
+	w.modTime, w.size = info.ModTime(), info.Size()
+	o, err := parseOwnership(w.path, data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", w.path, err)
+	}
+	w.current.Store(o)
+	return true, nil
+}
+
+// owner returns the team and cost center of group k. A nil watcher has no teams.
+func (w *ownershipWatcher) owner(k groupingKey) (string, string, bool) {
+	if w == nil {
+		return "", "", false
+	}
+	team, costCenter := w.current.Load().owner(k)
+	return team, costCenter, true
+}
+
+// watchOwnership reloads the ownership file every interval until the exporter stops.
+func (e *spanReportExporter) watchOwnership(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				changed, err := e.ownership.reload()
+				if err != nil {
+					e.logger.Warn("Failed to reload the ownership file, keeping the previous mapping", zap.Error(err))
+				} else if changed {
+					e.logger.Info("Reloaded the ownership file", zap.String("path", e.ownership.path))
+				}
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// setOwners fills in the team and cost center of each group.
+func (e *spanReportExporter) setOwners(groups []GroupCounts) {
+	for i := range groups {
+		g := &groups[i]
+		g.Team, g.CostCenter, _ = e.ownership.owner(g.key())
+	}
+}
+
+// TeamCounts is the sum of the groups owned by a team and cost center.
+type TeamCounts struct {
+	Team       string       `json:"team"`
+	CostCenter string       `json:"cost_center,omitempty"`
+	Groups     int          `json:"groups"`
+	Hourly     PeriodCounts `json:"hourly"`
+	Daily      PeriodCounts `json:"daily"`
+	Monthly    PeriodCounts `json:"monthly"`
+}
+
+// counts returns the counters of the team as a group, to be rendered like one.
+func (t TeamCounts) counts() GroupCounts {
+	return GroupCounts{Service: t.Team, Env: t.CostCenter, Hourly: t.Hourly, Daily: t.Daily, Monthly: t.Monthly}
+}
+
+// rollUpTeams sums the groups by team and cost center, sorted by team. Groups
+// without a team (no ownership file) are not rolled up.
+func rollUpTeams(groups []GroupCounts) []TeamCounts {
+	type teamKey struct{ team, costCenter string }
+	index := map[teamKey]int{}
+	var teams []TeamCounts
+	for _, g := range groups {
+		if g.Team == "" {
+			continue
+		}
+		k := teamKey{g.Team, g.CostCenter}
+		i, ok := index[k]
+		if !ok {
+			i = len(teams)
+			index[k] = i
+			teams = append(teams, TeamCounts{Team: g.Team, CostCenter: g.CostCenter})
+		}
+		t := &teams[i]
+		t.Groups++
+		t.Hourly.add(g.Hourly)
+		t.Daily.add(g.Daily)
+		t.Monthly.add(g.Monthly)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if teams[i].Team != teams[j].Team {
+			return teams[i].Team < teams[j].Team
+		}
+		return teams[i].CostCenter < teams[j].CostCenter
+	})
+	return teams
+}
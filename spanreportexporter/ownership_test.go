@@ -0,0 +1,94 @@
+package spanreportexporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestParseOwnership(t *testing.T) {
+	yamlRules, err := parseOwnership("owners.yaml", []byte(`
+owners:
+  - service: "order-*"
+    env: prod
+    team: commerce
+    cost_center: CC-1001
+  - service: "order-*"
+    team: commerce
+    cost_center: CC-2001
+  - service: auth-svc
+    team: identity
+`))
+	require.NoError(t, err)
+	csvRules, err := parseOwnership("owners.csv", []byte(`service,env,team,cost_center
+order-*,prod,commerce,CC-1001
+order-*,,commerce,CC-2001
+auth-svc,,identity,
+`))
+	require.NoError(t, err)
+	assert.Equal(t, yamlRules, csvRules)
+
+	for _, tc := range []struct {
+		key              groupingKey
+		team, costCenter string
+	}{
+		{groupingKey{service: "order-api", env: "prod"}, "commerce", "CC-1001"},
+		{groupingKey{service: "order-worker", env: "dev"}, "commerce", "CC-2001"},
+		{groupingKey{service: "auth-svc", env: "prod"}, "identity", ""},
+		{groupingKey{service: "legacy", env: "prod"}, unownedTeam, ""},
+	} {
+		team, costCenter := yamlRules.owner(tc.key)
+		assert.Equal(t, tc.team, team, tc.key)
+		assert.Equal(t, tc.costCenter, costCenter, tc.key)
+	}
+
+	_, err = parseOwnership("owners.yaml", []byte("owners:\n  - service: api\n"))
+	assert.ErrorContains(t, err, "team is required")
+	_, err = parseOwnership("owners.csv", []byte("service,env\napi,prod\n"))
+	assert.ErrorContains(t, err, "no team column")
+}
+
+func TestCollectReport_RollsUpTeams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owners.csv")
+	require.NoError(t, os.WriteFile(path, []byte("service,team\norder-*,commerce\n"), 0o644))
+	exp := &spanReportExporter{
+		logger:    componenttest.NewNopTelemetrySettings().Logger,
+		ownership: &ownershipWatcher{path: path},
+	}
+	changed, err := exp.ownership.reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	exp.statsFor(groupingKey{service: "order-api", env: "prod"}).hourly.Store(10)
+	exp.statsFor(groupingKey{service: "order-worker", env: "prod"}).hourly.Store(5)
+	exp.statsFor(groupingKey{service: "auth-svc", env: "prod"}).hourly.Store(3)
+
+	report := exp.collectReport(time.Now())
+	require.Len(t, report.Teams, 2)
+	assert.Equal(t, TeamCounts{Team: "commerce", Groups: 2, Hourly: PeriodCounts{Total: 15}}, report.Teams[0])
+	assert.Equal(t, TeamCounts{Team: unownedTeam, Groups: 1, Hourly: PeriodCounts{Total: 3}}, report.Teams[1])
+
+	lines, err := formatTextLines(report)
+	require.NoError(t, err)
+	assert.Contains(t, lines[3], "team:commerce, cost_center:, groups:2 | Hourly(Total:15, HTTP:0, SQL:0)")
+	assert.Contains(t, lines[3], "| Share(Hourly:83.3%,")
+
+	// An invalid file keeps the previous mapping, a valid one replaces it
+	require.NoError(t, os.WriteFile(path, []byte("service,env\n"), 0o644))
+	_, err = exp.ownership.reload()
+	assert.Error(t, err)
+	team, _, _ := exp.ownership.owner(groupingKey{service: "order-api", env: "prod"})
+	assert.Equal(t, "commerce", team)
+
+	require.NoError(t, os.WriteFile(path, []byte("service,team\n*,platform\n"), 0o644))
+	changed, err = exp.ownership.reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	team, _, _ = exp.ownership.owner(groupingKey{service: "order-api", env: "prod"})
+	assert.Equal(t, "platform", team)
+}
@@ -0,0 +1,273 @@
+package spanreportexporter
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationConfig controls the rotation of the report file.
+type RotationConfig struct {
+	// MaxSizeMB rotates the file when it would grow beyond this size. 0 disables size-based rotation.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+	// MaxFiles is the number of rotated files to keep. 0 keeps all of them.
+	MaxFiles int `mapstructure:"max_files"`
+	// Compress gzips rotated files.
+	Compress bool `mapstructure:"compress"`
+}
+
+// reportFile appends reports to a file whose path may contain strftime-like
+// placeholders (%Y, %m, %d, %H), e.g. "span_report-%Y-%m.txt" for one file per month.
+// Writes and rotations are serialized, so no line is lost while a file is rotated.
+//
+// Each report is written with a single write call and is either written
+// completely or not at all. Lines that could not be written are kept in memory
+// (up to maxPending lines, oldest dropped first) and written with the next report.
+type reportFile struct {
+	mu         sync.Mutex
+	template   string
+	rotation   RotationConfig
+	fsync      bool
+	maxPending int
+	current    string   // path of the file written last
+	pending    []string // lines of failed writes, to be retried
+	dropped    uint64   // lines dropped because pending was full
+}
+
+func newReportFile(template string, rotation RotationConfig, fsync bool, maxPending int) *reportFile {
+	return &reportFile{template: template, rotation: rotation, fsync: fsync, maxPending: maxPending}
+}
+
+// expandPathTemplate replaces the placeholders of template with the values of t.
+func expandPathTemplate(template string, t time.Time) string {
+	return strings.NewReplacer(
+		"%Y", t.Format("2006"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%H", t.Format("15"),
+		"%%", "%",
+	).Replace(template)
+}
+
+// globPathTemplate returns a glob pattern that matches every file the template can expand to.
+func globPathTemplate(template string) string {
+	return strings.NewReplacer(
+		"%Y", "*",
+		"%m", "*",
+		"%d", "*",
+		"%H", "*",
+		"%%", "%",
+	).Replace(template)
+}
+
+// write appends the pending lines and lines to the file for time t, rotating it first if needed.
+// On failure, the lines are kept for the next call.
+func (f *reportFile) write(t time.Time, lines []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all := append(f.pending, lines...)
+	if err := f.writeLines(t, all); err != nil {
+		f.keepPending(all)
+		return err
+	}
+	f.pending = nil
+	if err := f.prune(); err != nil {
+		return fmt.Errorf("failed to remove old report files: %w", err)
+	}
+	return nil
+}
+
+// status returns the number of lines waiting to be written, and the number of
+// lines given up because too many were waiting.
+func (f *reportFile) status() (pending int, dropped uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pending), f.dropped
+}
+
+func (f *reportFile) keepPending(lines []string) {
+	if f.maxPending > 0 && len(lines) > f.maxPending {
+		f.dropped += uint64(len(lines) - f.maxPending)
+		lines = lines[len(lines)-f.maxPending:]
+	}
+	f.pending = lines
+}
+
+func (f *reportFile) writeLines(t time.Time, lines []string) error {
+	path := expandPathTemplate(f.template, t)
+	if f.current != "" && path != f.current {
+		// The calendar period of the previous file is over
+		f.finish(f.current)
+	}
+	f.current = path
+
+	var buf strings.Builder
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	if err := f.rotateBySize(path, int64(buf.Len()), t); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	_, err = file.WriteString(buf.String())
+	if err == nil && f.fsync {
+		err = file.Sync()
+	}
+	if err != nil {
+		// Do not leave a truncated line behind (e.g. when the disk is full)
+		file.Truncate(info.Size())
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rotateBySize renames path if appending n bytes would exceed the size limit.
+func (f *reportFile) rotateBySize(path string, n int64, t time.Time) error {
+	if f.rotation.MaxSizeMB <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 || info.Size()+n <= int64(f.rotation.MaxSizeMB)*1024*1024 {
+		return nil
+	}
+
+	rotated := path + "." + t.Format("20060102-150405")
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s.%s-%d", path, t.Format("20060102-150405"), i)
+	}
+	if err := os.Rename(path, rotated); err != nil {
+		return err
+	}
+	f.finish(rotated)
+	return nil
+}
+
+// finish compresses a file that will not be written anymore, if enabled.
+// Failing to compress is not fatal: the file is simply kept as it is.
+func (f *reportFile) finish(path string) {
+	if !f.rotation.Compress || strings.HasSuffix(path, ".gz") || !fileExists(path) {
+		return
+	}
+	if err := gzipFile(path); err == nil {
+		os.Remove(path)
+	}
+}
+
+// rotationSuffix matches the suffix added by rotateBySize, e.g. ".20251218-100000-1".
+var rotationSuffix = regexp.MustCompile(`\.\d{8}-\d{6}(-\d+)?$`)
+
+// isRotatedFile reports whether path is a file of the template, with the
+// suffixes added by rotateBySize and finish, so that other files next to the
+// report (e.g. span_report.txt.bak) are never pruned.
+func isRotatedFile(pattern, path string) bool {
+	path = strings.TrimSuffix(path, ".gz")
+	path = rotationSuffix.ReplaceAllString(path, "")
+	ok, _ := filepath.Match(pattern, path)
+	return ok
+}
+
+// prune removes the oldest rotated files beyond MaxFiles.
+func (f *reportFile) prune() error {
+	if f.rotation.MaxFiles <= 0 {
+		return nil
+	}
+	// Glob drops a leading "./", so paths are compared once cleaned
+	pattern := filepath.Clean(globPathTemplate(f.template))
+	var candidates []string
+	for _, p := range []string{pattern, pattern + ".*"} {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			if m = filepath.Clean(m); isRotatedFile(pattern, m) {
+				candidates = append(candidates, m)
+			}
+		}
+	}
+
+	type rotatedFile struct {
+		path    string
+		modTime time.Time
+	}
+	var rotated []rotatedFile
+	seen := map[string]bool{filepath.Clean(f.current): true}
+	for _, p := range candidates {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			rotated = append(rotated, rotatedFile{p, info.ModTime()})
+		}
+	}
+	if len(rotated) <= f.rotation.MaxFiles {
+		return nil
+	}
+
+	// Newest first
+	sort.Slice(rotated, func(i, j int) bool {
+		if !rotated[i].modTime.Equal(rotated[j].modTime) {
+			return rotated[i].modTime.After(rotated[j].modTime)
+		}
+		return rotated[i].path > rotated[j].path
+	})
+	for _, r := range rotated[f.rotation.MaxFiles:] {
+		if err := os.Remove(r.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func gzipFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := dst.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	if _, err := io.Copy(zw, src); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,152 @@
+package spanreportexporter
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPathTemplate(t *testing.T) {
+	ts := time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC)
+	assert.Equal(t, "span_report-2025-12.txt", expandPathTemplate("span_report-%Y-%m.txt", ts))
+	assert.Equal(t, "logs/20251218-09.txt", expandPathTemplate("logs/%Y%m%d-%H.txt", ts))
+	assert.Equal(t, "100%.txt", expandPathTemplate("100%%.txt", ts))
+	assert.Equal(t, "span_report-*-*.txt", globPathTemplate("span_report-%Y-%m.txt"))
+}
+
+func TestReportFile_CalendarRotation(t *testing.T) {
+	dir := t.TempDir()
+	f := newReportFile(filepath.Join(dir, "span_report-%Y-%m-%d.txt"), RotationConfig{Compress: true, MaxFiles: 1}, false, 0)
+
+	day1 := time.Date(2025, 12, 17, 23, 59, 59, 0, time.UTC)
+	require.NoError(t, f.write(day1, []string{"day1\n"}))
+	require.NoError(t, f.write(day1.Add(24*time.Hour), []string{"day2\n"}))
+	require.NoError(t, f.write(day1.Add(48*time.Hour), []string{"day3\n"}))
+
+	// The current file is plain text, the previous one is compressed, the oldest one is pruned
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"span_report-2025-12-18.txt.gz", "span_report-2025-12-19.txt"}, names)
+	assert.Equal(t, "day2\n", readGzip(t, filepath.Join(dir, "span_report-2025-12-18.txt.gz")))
+}
+
+func TestReportFile_SizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "span_report.txt")
+	f := newReportFile(path, RotationConfig{MaxSizeMB: 1, MaxFiles: 2}, false, 0)
+
+	line := string(make([]byte, 400*1024)) + "\n"
+	now := time.Date(2025, 12, 18, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, f.write(now.Add(time.Duration(i)*time.Second), []string{line}))
+	}
+
+	// 7 lines of 400KB: 2 lines per 1MB file, only the 2 newest rotated files are kept
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(line)), info.Size())
+}
+
+func TestReportFile_PruneRelativePath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.WriteFile("span_report.txt.bak", []byte("backup"), 0644))
+	f := newReportFile("./span_report.txt", RotationConfig{MaxSizeMB: 1, MaxFiles: 2}, false, 0)
+
+	line := string(make([]byte, 600*1024)) + "\n"
+	now := time.Date(2025, 12, 18, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, f.write(now.Add(time.Duration(i)*time.Second), []string{line}))
+	}
+
+	// The current file does not count as a rotated file, and other files are kept
+	matches, err := filepath.Glob("span_report.txt.*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"span_report.txt.20251218-100003", "span_report.txt.20251218-100004", "span_report.txt.bak"}, matches)
+}
+
+func TestIsRotatedFile(t *testing.T) {
+	pattern := "span_report-*-*.txt"
+	assert.True(t, isRotatedFile(pattern, "span_report-2025-12.txt"))
+	assert.True(t, isRotatedFile(pattern, "span_report-2025-12.txt.gz"))
+	assert.True(t, isRotatedFile(pattern, "span_report-2025-12.txt.20251218-100000"))
+	assert.True(t, isRotatedFile(pattern, "span_report-2025-12.txt.20251218-100000-2.gz"))
+	assert.False(t, isRotatedFile(pattern, "span_report-2025-12.txt.bak"))
+	assert.False(t, isRotatedFile(pattern, "span_report-2025-12.txt.20251218"))
+}
+
+func TestReportFile_ConcurrentWritesDuringRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "span_report.txt")
+	f := newReportFile(path, RotationConfig{MaxSizeMB: 1}, true, 0)
+
+	line := string(make([]byte, 100*1024-1)) + "\n"
+	now := time.Date(2025, 12, 18, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, f.write(now, []string{line}))
+		}()
+	}
+	wg.Wait()
+
+	// Every line ends up in exactly one of the files
+	matches, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+	var total int64
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		require.NoError(t, err)
+		total += info.Size()
+	}
+	assert.Equal(t, int64(40*len(line)), total)
+}
+
+func readGzip(t *testing.T, path string) string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestReportFile_FailedWritesAreRetried(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reports", "span_report.txt")
+	f := newReportFile(path, RotationConfig{}, true, 3)
+	now := time.Date(2025, 12, 18, 10, 0, 0, 0, time.UTC)
+
+	// The directory does not exist yet: the lines are kept, up to 3 of them
+	assert.Error(t, f.write(now, []string{"a\n", "b\n"}))
+	assert.Error(t, f.write(now, []string{"c\n", "d\n"}))
+	pending, dropped := f.status()
+	assert.Equal(t, 3, pending)
+	assert.Equal(t, uint64(1), dropped)
+
+	// Once writable again, the kept lines are written before the new ones
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, f.write(now, []string{"e\n"}))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "b\nc\nd\ne\n", string(content))
+	pending, _ = f.status()
+	assert.Equal(t, 0, pending)
+}
@@ -0,0 +1,187 @@
+package spanreportexporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/collector/client"
+)
+
+// SendersConfig controls the breakdown of the received data by sender.
+type SendersConfig struct {
+	// Enabled turns on the breakdown.
+	Enabled bool `mapstructure:"enabled"`
+	// TopN is the number of senders kept in each period.
+	TopN int `mapstructure:"top_n"`
+	// AuthAttribute is the attribute of the authenticator naming the principal.
+	// Defaults to "subject", then "username".
+	AuthAttribute string `mapstructure:"auth_attribute"`
+}
+
+func (c SendersConfig) validate() error {
+	if c.Enabled && c.TopN < 1 {
+		return fmt.Errorf("senders.top_n must be at least 1")
+	}
+	return nil
+}
+
+// defaultAuthAttributes name the principal with the oidc and basicauth authenticators.
+var defaultAuthAttributes = []string{"subject", "username"}
+
+// senderTableFactor is the number of senders tracked per sender reported, so
+// that a sender climbing into the top N has been counted for most of its data.
+const senderTableFactor = 10
+
+// SenderCounts is the data received from one sender in a period. The counts
+// start when the sender entered the tracked senders; Error is the number of
+// items it may have sent before, when more senders than tracked were seen.
+type SenderCounts struct {
+	Sender     string `json:"sender"`
+	Spans      uint64 `json:"spans"`
+	Logs       uint64 `json:"logs"`
+	DataPoints uint64 `json:"data_points"`
+	Error      uint64 `json:"error,omitempty"`
+}
+
+// items returns the number of spans, log records and data points, which ranks the senders.
+func (c SenderCounts) items() uint64 {
+	return c.Spans + c.Logs + c.DataPoints
+}
+
+// SenderPeriods holds the top senders of each period, by number of items.
+type SenderPeriods struct {
+	Hourly  []SenderCounts `json:"hourly"`
+	Daily   []SenderCounts `json:"daily"`
+	Monthly []SenderCounts `json:"monthly"`
+}
+
+// senders returns a pointer to the senders of one period.
+func (s *SenderPeriods) senders(p period) *[]SenderCounts {
+	switch p {
+	case periodDaily:
+		return &s.Daily
+	case periodMonthly:
+		return &s.Monthly
+	default:
+		return &s.Hourly
+	}
+}
+
+// senderTracker counts the data of the heaviest senders of each period with
+// the Space-Saving algorithm: once the table is full, a new sender replaces
+// the one with the fewest items, whose count becomes the error of the new one.
+type senderTracker struct {
+	topN           int
+	capacity       int
+	authAttributes []string
+	mu             sync.Mutex
+	tables         [len(allPeriods)]map[string]*SenderCounts
+}
+
+func newSenderTracker(c SendersConfig) *senderTracker {
+	t := &senderTracker{topN: c.TopN, capacity: c.TopN * senderTableFactor, authAttributes: defaultAuthAttributes}
+	if c.AuthAttribute != "" {
+		t.authAttributes = []string{c.AuthAttribute}
+	}
+	for p := range t.tables {
+		t.tables[p] = map[string]*SenderCounts{}
+	}
+	return t
+}
+
+// senderFromContext returns the principal of the request when an
+// authenticator is configured, and the address of the peer otherwise.
+func (t *senderTracker) senderFromContext(ctx context.Context) string {
+	info := client.FromContext(ctx)
+	if info.Auth != nil {
+		for _, name := range t.authAttributes {
+			if v, ok := info.Auth.GetAttribute(name).(string); ok && v != "" {
+				return v
+			}
+		}
+	}
+	if info.Addr != nil {
+		// The port changes with each connection
+		if host, _, err := net.SplitHostPort(info.Addr.String()); err == nil {
+			return host
+		}
+		return info.Addr.String()
+	}
+	return "unknown"
+}
+
+// add counts the items of one request. It does nothing when the breakdown is disabled.
+func (t *senderTracker) add(ctx context.Context, c SenderCounts) {
+	if t == nil || c.items() == 0 {
+		return
+	}
+	sender := t.senderFromContext(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, table := range t.tables {
+		s, ok := table[sender]
+		if !ok {
+			s = &SenderCounts{Sender: sender}
+			if len(table) >= t.capacity {
+				least := minSender(table)
+				delete(table, least.Sender)
+				s.Error = least.items() + least.Error
+			}
+			table[sender] = s
+		}
+		s.Spans += c.Spans
+		s.Logs += c.Logs
+		s.DataPoints += c.DataPoints
+	}
+}
+
+// minSender returns the sender with the fewest items, including the error.
+func minSender(table map[string]*SenderCounts) *SenderCounts {
+	var least *SenderCounts
+	for _, s := range table {
+		if least == nil || s.items()+s.Error < least.items()+least.Error {
+			least = s
+		}
+	}
+	return least
+}
+
+// top returns the top N senders of one period, and forgets all of them if reset is set.
+func (t *senderTracker) top(p period, reset bool) []SenderCounts {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	senders := make([]SenderCounts, 0, len(t.tables[p]))
+	for _, s := range t.tables[p] {
+		senders = append(senders, *s)
+	}
+	if reset {
+		t.tables[p] = map[string]*SenderCounts{}
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		a, b := senders[i].items()+senders[i].Error, senders[j].items()+senders[j].Error
+		if a != b {
+			return a > b
+		}
+		return senders[i].Sender < senders[j].Sender
+	})
+	if len(senders) > t.topN {
+		senders = senders[:t.topN]
+	}
+	return senders
+}
+
+// periods returns the top senders of every period, resetting the finished ones.
+// It returns nil when the breakdown is disabled.
+func (t *senderTracker) periods(finished map[period]bool) *SenderPeriods {
+	if t == nil {
+		return nil
+	}
+	s := &SenderPeriods{}
+	for _, p := range allPeriods {
+		*s.senders(p) = t.top(p, finished[p])
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package spanreportexporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// authData is the client.AuthData set by an authenticator.
+type authData map[string]any
+
+func (a authData) GetAttribute(name string) any { return a[name] }
+
+func (a authData) GetAttributeNames() []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	return names
+}
+
+func senderContext(addr string, auth client.AuthData) context.Context {
+	info := client.Info{Auth: auth}
+	if addr != "" {
+		info.Addr = net.TCPAddrFromAddrPort(netip.MustParseAddrPort(addr))
+	}
+	return client.NewContext(context.Background(), info)
+}
+
+func TestConsumeTraces_CountsTopSenders(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), senders: newSenderTracker(SendersConfig{Enabled: true, TopN: 2})}
+
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.1:50001", nil), tenantTraces("api", "api", "web")))
+	// Another connection of the same host
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.1:50002", nil), tenantTraces("api")))
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.2:50001", nil), tenantTraces("api", "api")))
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.3:50001", nil), tenantTraces("api")))
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	require.NoError(t, exp.ConsumeLogs(senderContext("10.0.0.2:50001", nil), ld))
+
+	report := exp.collectReport(time.Now())
+	require.NotNil(t, report.Senders)
+	assert.Equal(t, []SenderCounts{
+		{Sender: "10.0.0.1", Spans: 4},
+		{Sender: "10.0.0.2", Spans: 2, Logs: 1},
+	}, report.Senders.Hourly)
+	assert.Equal(t, report.Senders.Hourly, report.Senders.Monthly)
+}
+
+func TestConsumeTraces_SenderIsAuthPrincipal(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), senders: newSenderTracker(SendersConfig{Enabled: true, TopN: 10})}
+
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.1:50001", authData{"subject": "ingest-bot"}), tenantTraces("api")))
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.2:50001", authData{"username": "alice"}), tenantTraces("api")))
+	require.NoError(t, exp.ConsumeTraces(senderContext("", nil), tenantTraces("api")))
+
+	senders := map[string]uint64{}
+	for _, s := range exp.senders.top(periodHourly, false) {
+		senders[s.Sender] = s.Spans
+	}
+	assert.Equal(t, map[string]uint64{"ingest-bot": 1, "alice": 1, "unknown": 1}, senders)
+}
+
+func TestSenderTracker_ReplacesTheLeastSender(t *testing.T) {
+	tracker := newSenderTracker(SendersConfig{Enabled: true, TopN: 1})
+	ctx := func(i int) context.Context { return senderContext(fmt.Sprintf("10.0.0.%d:4317", i), nil) }
+
+	// Fill the table, then a new sender replaces the one with the fewest items
+	for i := range senderTableFactor {
+		tracker.add(ctx(i), SenderCounts{Spans: uint64(10 + i)})
+	}
+	tracker.add(ctx(100), SenderCounts{Spans: 5})
+	tracker.mu.Lock()
+	_, ok := tracker.tables[periodHourly]["10.0.0.0"]
+	s := *tracker.tables[periodHourly]["10.0.0.100"]
+	tracker.mu.Unlock()
+	assert.False(t, ok)
+	assert.Equal(t, SenderCounts{Sender: "10.0.0.100", Spans: 5, Error: 10}, s)
+
+	// A heavy sender arriving late makes it to the top
+	tracker.add(ctx(200), SenderCounts{Spans: 1000})
+	top := tracker.top(periodHourly, false)
+	require.Len(t, top, 1)
+	assert.Equal(t, "10.0.0.200", top[0].Sender)
+}
+
+func TestCollectReport_ResetsSendersOfFinishedPeriods(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), senders: newSenderTracker(SendersConfig{Enabled: true, TopN: 10})}
+	require.NoError(t, exp.ConsumeTraces(senderContext("10.0.0.1:4317", nil), tenantTraces("api")))
+
+	exp.lastExportTime = time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC)
+	report := exp.collectReport(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC))
+	assert.Equal(t, []SenderCounts{{Sender: "10.0.0.1", Spans: 1}}, report.Senders.Hourly, "the finished hour is reported")
+	exp.lastExportTime = time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)
+	report = exp.collectReport(time.Date(2026, 1, 1, 1, 30, 0, 0, time.UTC))
+	assert.Empty(t, report.Senders.Hourly)
+	assert.Equal(t, []SenderCounts{{Sender: "10.0.0.1", Spans: 1}}, report.Senders.Daily)
+}
@@ -0,0 +1,77 @@
+package spanreportexporter
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// flushReport writes a report right away. Counters are only reset if a period
+// boundary has passed since the last report, exactly as on a scheduled tick.
+func (e *spanReportExporter) flushReport() {
+	e.logger.Info("Writing report on demand")
+	e.rotateAndWrite(time.Now())
+}
+
+// logSnapshot writes the current counters of every group, for all periods, to the log.
+func (e *spanReportExporter) logSnapshot() {
+	rows := e.snapshot()
+	// lastExportTime is written by rotateAndWrite
+	e.reportMu.Lock()
+	lastExport := e.lastExportTime
+	e.reportMu.Unlock()
+	e.logger.Info("Snapshot", zap.Int("groups", len(rows)), zap.Time("last_export", lastExport))
+	for _, r := range rows {
+		var logFields []zap.Field
+		if e.hasSignal(signalLogs) {
+			logFields = []zap.Field{
+				zap.Uint64("hourly_logs", r.Hourly.Logs),
+				zap.Uint64("hourly_log_errors", r.Hourly.LogErrors),
+				zap.Uint64("daily_logs", r.Daily.Logs),
+				zap.Uint64("daily_log_errors", r.Daily.LogErrors),
+				zap.Uint64("monthly_logs", r.Monthly.Logs),
+				zap.Uint64("monthly_log_errors", r.Monthly.LogErrors),
+			}
+		}
+		if e.hasSignal(signalMetrics) {
+			logFields = append(logFields,
+				zap.Uint64("hourly_data_points", r.Hourly.DataPoints),
+				zap.Uint64("hourly_series", r.Hourly.Series),
+				zap.Uint64("daily_data_points", r.Daily.DataPoints),
+				zap.Uint64("daily_series", r.Daily.Series),
+				zap.Uint64("monthly_data_points", r.Monthly.DataPoints),
+				zap.Uint64("monthly_series", r.Monthly.Series),
+			)
+		}
+		e.logger.Info("Snapshot group", append([]zap.Field{
+			zap.String("tenant", r.Tenant),
+			zap.String("namespace", r.Namespace),
+			zap.String("service", r.Service),
+			zap.String("environment", r.Env),
+			zap.Uint64("hourly_total", r.Hourly.Total),
+			zap.Uint64("hourly_http", r.Hourly.HTTP),
+			zap.Uint64("hourly_sql", r.Hourly.SQL),
+			zap.Uint64("hourly_internal", r.Hourly.Internal),
+			zap.Uint64("hourly_server", r.Hourly.Server),
+			zap.Uint64("hourly_client", r.Hourly.Client),
+			zap.Uint64("hourly_producer", r.Hourly.Producer),
+			zap.Uint64("hourly_consumer", r.Hourly.Consumer),
+			zap.Uint64("daily_total", r.Daily.Total),
+			zap.Uint64("daily_http", r.Daily.HTTP),
+			zap.Uint64("daily_sql", r.Daily.SQL),
+			zap.Uint64("daily_internal", r.Daily.Internal),
+			zap.Uint64("daily_server", r.Daily.Server),
+			zap.Uint64("daily_client", r.Daily.Client),
+			zap.Uint64("daily_producer", r.Daily.Producer),
+			zap.Uint64("daily_consumer", r.Daily.Consumer),
+			zap.Uint64("monthly_total", r.Monthly.Total),
+			zap.Uint64("monthly_http", r.Monthly.HTTP),
+			zap.Uint64("monthly_sql", r.Monthly.SQL),
+			zap.Uint64("monthly_internal", r.Monthly.Internal),
+			zap.Uint64("monthly_server", r.Monthly.Server),
+			zap.Uint64("monthly_client", r.Monthly.Client),
+			zap.Uint64("monthly_producer", r.Monthly.Producer),
+			zap.Uint64("monthly_consumer", r.Monthly.Consumer),
+		}, logFields...)...)
+	}
+}
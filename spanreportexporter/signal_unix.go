@@ -0,0 +1,33 @@
+//go:build !windows
+
+package spanreportexporter
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleSignals writes a report on SIGUSR1 and logs a snapshot on SIGUSR2.
+// signal.Notify only adds a receiver for these two signals, so the collector's
+// own handling of SIGINT/SIGTERM/SIGHUP is left untouched.
+func (e *spanReportExporter) handleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case sig := <-ch:
+				switch sig {
+				case syscall.SIGUSR1:
+					e.flushReport()
+				case syscall.SIGUSR2:
+					e.logSnapshot()
+				}
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package spanreportexporter
+
+// handleSignals does nothing on Windows, which has no SIGUSR1/SIGUSR2.
+func (e *spanReportExporter) handleSignals() {}
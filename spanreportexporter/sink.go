@@ -0,0 +1,390 @@
+package spanreportexporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Report is the result of one report tick: the counters of every group,
+// labeled with the time returned by reportTime. Total sums up all groups, and
+// EnvTotals each environment when env_subtotals is enabled (see summarize).
+type Report struct {
+	Time time.Time `json:"time"`
+	// Signals lists the signals counted by the exporter, e.g. traces and logs
+	Signals   []string      `json:"signals"`
+	Groups    []GroupCounts `json:"groups"`
+	Total     GroupCounts   `json:"total"`
+	EnvTotals []GroupCounts `json:"env_totals,omitempty"`
+	// Subtotals sums the groups of each namespace and service, with hierarchy
+	Subtotals []GroupCounts `json:"subtotals,omitempty"`
+	// TenantTotals sums the groups of each tenant, with tenant_header
+	TenantTotals []GroupCounts `json:"tenant_totals,omitempty"`
+	// Teams sums the groups by owner, with an ownership file
+	Teams []TeamCounts `json:"teams,omitempty"`
+	// Senders lists the top senders of each period, with senders enabled
+	Senders *SenderPeriods `json:"senders,omitempty"`
+}
+
+// ReportSink is an output of the periodic reports. Sinks are written one after
+// another; a sink that fails is logged and counted, and does not prevent the
+// other sinks from being written. Sinks that also implement io.Closer are
+// closed on shutdown.
+type ReportSink interface {
+	Write(ctx context.Context, report Report) error
+}
+
+// EventSink is implemented by sinks that are also notified of events such as
+// anomalies, in addition to the periodic reports.
+type EventSink interface {
+	WriteEvent(ctx context.Context, event Event) error
+}
+
+// EventTypeAnomaly is the type of the events sent for an Anomaly.
+const EventTypeAnomaly = "anomaly"
+
+// Event is a notification sent to the EventSinks.
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Anomaly *Anomaly  `json:"anomaly,omitempty"`
+}
+
+// Sink types accepted in SinkConfig.Type.
+const (
+	sinkTypeFile    = "file"
+	sinkTypeStdout  = "stdout"
+	sinkTypeJSONL   = "jsonl"
+	sinkTypeWebhook = "webhook"
+	sinkTypeSQLite  = "sqlite"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// SinkConfig configures one named report output. Which fields are used depends on Type.
+type SinkConfig struct {
+	// Name identifies the sink in logs. Defaults to Type.
+	Name string `mapstructure:"name"`
+	// Type is one of file, stdout, jsonl, webhook and sqlite.
+	Type string `mapstructure:"type"`
+	// Path is the output file of the file, jsonl and sqlite sinks.
+	Path string `mapstructure:"path"`
+	// Rotation, Fsync and MaxPendingLines apply to the file and jsonl sinks, like the top-level options.
+	Rotation        RotationConfig `mapstructure:"rotation"`
+	Fsync           bool           `mapstructure:"fsync"`
+	MaxPendingLines int            `mapstructure:"max_pending_lines"`
+	// Template is a text/template that renders each report of the file and stdout sinks,
+	// instead of the default line format.
+	Template string `mapstructure:"template"`
+	// URL, Headers and Timeout configure the webhook sink, which POSTs each report as JSON.
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	Timeout string            `mapstructure:"timeout"`
+	// Events also POSTs events such as anomalies to the webhook.
+	Events bool `mapstructure:"events"`
+}
+
+func (c SinkConfig) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+func (c SinkConfig) validate() error {
+	switch c.Type {
+	case sinkTypeFile, sinkTypeJSONL, sinkTypeSQLite:
+		if c.Path == "" {
+			return fmt.Errorf("path is required for %s sinks", c.Type)
+		}
+	case sinkTypeStdout:
+	case sinkTypeWebhook:
+		if c.URL == "" {
+			return fmt.Errorf("url is required for webhook sinks")
+		}
+		if c.Timeout != "" {
+			if _, err := time.ParseDuration(c.Timeout); err != nil {
+				return fmt.Errorf("invalid timeout: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q (must be file, stdout, jsonl, webhook or sqlite)", c.Type)
+	}
+	if c.MaxPendingLines < 0 {
+		return fmt.Errorf("max_pending_lines must not be negative")
+	}
+	if c.Template != "" {
+		if c.Type != sinkTypeFile && c.Type != sinkTypeStdout {
+			return fmt.Errorf("template is only supported by file and stdout sinks")
+		}
+		if _, err := parseReportTemplate(c.Template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return nil
+}
+
+// namedSink is an opened sink together with the name it is logged with.
+type namedSink struct {
+	name string
+	ReportSink
+}
+
+// openSinks opens every configured sink. If one fails, the ones already opened are closed.
+func openSinks(configs []SinkConfig) ([]namedSink, error) {
+	sinks := []namedSink{}
+	for _, c := range configs {
+		s, err := openSink(c)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, fmt.Errorf("failed to open sink %s: %w", c.name(), err)
+		}
+		sinks = append(sinks, namedSink{name: c.name(), ReportSink: s})
+	}
+	return sinks, nil
+}
+
+func openSink(c SinkConfig) (ReportSink, error) {
+	maxPending := c.MaxPendingLines
+	if maxPending == 0 {
+		maxPending = defaultMaxPendingLines
+	}
+	format := formatTextLines
+	if c.Template != "" {
+		tmpl, err := parseReportTemplate(c.Template)
+		if err != nil {
+			return nil, err
+		}
+		format = templateFormatter(tmpl)
+	}
+	switch c.Type {
+	case sinkTypeFile:
+		return &fileSink{file: newReportFile(c.Path, c.Rotation, c.Fsync, maxPending), format: format}, nil
+	case sinkTypeJSONL:
+		return &fileSink{file: newReportFile(c.Path, c.Rotation, c.Fsync, maxPending), format: formatJSONLines}, nil
+	case sinkTypeStdout:
+		return &stdoutSink{w: os.Stdout, format: format}, nil
+	case sinkTypeWebhook:
+		timeout, _ := time.ParseDuration(c.Timeout)
+		if timeout <= 0 {
+			timeout = defaultWebhookTimeout
+		}
+		return &webhookSink{url: c.URL, headers: c.Headers, events: c.Events, client: &http.Client{Timeout: timeout}}, nil
+	case sinkTypeSQLite:
+		return openSQLiteStore(c.Path)
+	}
+	return nil, fmt.Errorf("unknown sink type %q", c.Type)
+}
+
+func closeSinks(sinks []namedSink) {
+	for _, s := range sinks {
+		if c, ok := s.ReportSink.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// fileSink appends the formatted lines of each report to a reportFile.
+type fileSink struct {
+	file   *reportFile
+	format func(Report) ([]string, error)
+}
+
+func (s *fileSink) Write(_ context.Context, r Report) error {
+	lines, err := s.format(r)
+	if err != nil {
+		return err
+	}
+	return s.file.write(r.Time, lines)
+}
+
+// pendingReporter is implemented by sinks that keep failed writes for a retry.
+type pendingReporter interface {
+	status() (pending int, dropped uint64)
+}
+
+// status returns the pending and dropped lines of the file, see reportFile.status.
+func (s *fileSink) status() (int, uint64) {
+	return s.file.status()
+}
+
+// stdoutSink prints the text report, for running without the TUI.
+type stdoutSink struct {
+	w      io.Writer
+	format func(Report) ([]string, error)
+}
+
+func (s *stdoutSink) Write(_ context.Context, r Report) error {
+	lines, err := s.format(r)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(s.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookSink POSTs each report as a JSON document, and events if enabled.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	events  bool
+	client  *http.Client
+}
+
+func (s *webhookSink) Write(ctx context.Context, r Report) error {
+	return s.post(ctx, r)
+}
+
+func (s *webhookSink) WriteEvent(ctx context.Context, ev Event) error {
+	if !s.events {
+		return nil
+	}
+	return s.post(ctx, ev)
+}
+
+func (s *webhookSink) post(ctx context.Context, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// formatTextLines renders one line per group, in the format of span_report.txt,
+// with the share of the total and the change versus earlier periods, followed by
+// the namespace and service subtotals, the tenant totals, the team roll-ups,
+// the environment subtotals and the grand total.
+func formatTextLines(r Report) ([]string, error) {
+	var lines []string
+	displayTime := r.Time.Format("2006-01-02 15:04:05")
+	logs := slices.Contains(r.Signals, signalLogs)
+	metrics := slices.Contains(r.Signals, signalMetrics)
+
+	for _, g := range r.Groups {
+		line := formatCountsLine(displayTime, groupLabel(g), g, logs, metrics)
+		line += formatShare(g, r.Total)
+		line += fmt.Sprintf(" | Change(LastHour:%s, HourYesterday:%s, DayLastWeek:%s, LastMonth:%s)\n",
+			formatChange(g.Changes.LastHour),
+			formatChange(g.Changes.HourYesterday),
+			formatChange(g.Changes.DayLastWeek),
+			formatChange(g.Changes.LastMonth),
+		)
+		if a := g.Anomaly; a != nil {
+			line = strings.TrimSuffix(line, "\n") + fmt.Sprintf(" | Anomaly(Count:%d, Expected:%.0f, Score:%+.1f)\n",
+				a.Count, a.Expected, a.Score)
+		}
+		lines = append(lines, line)
+	}
+	for _, g := range r.Subtotals {
+		lines = append(lines, formatCountsLine(displayTime, groupLabel(g), g, logs, metrics)+formatShare(g, r.Total)+"\n")
+	}
+	for _, g := range r.TenantTotals {
+		lines = append(lines, formatCountsLine(displayTime, groupLabel(g), g, logs, metrics)+formatShare(g, r.Total)+"\n")
+	}
+	for _, t := range r.Teams {
+		label := fmt.Sprintf("team:%s, cost_center:%s, groups:%d", t.Team, t.CostCenter, t.Groups)
+		lines = append(lines, formatCountsLine(displayTime, label, t.counts(), logs, metrics)+formatShare(t.counts(), r.Total)+"\n")
+	}
+	for _, g := range r.EnvTotals {
+		lines = append(lines, formatCountsLine(displayTime, groupLabel(g), g, logs, metrics)+"\n")
+	}
+	if len(r.Groups) > 0 || !r.Total.isZero() {
+		lines = append(lines, formatCountsLine(displayTime, groupLabel(r.Total), r.Total, logs, metrics)+"\n")
+	}
+
+	return lines, nil
+}
+
+func groupLabel(g GroupCounts) string {
+	label := fmt.Sprintf("service:%s, env:%s", g.Service, g.Env)
+	if g.Namespace != "" {
+		label = fmt.Sprintf("namespace:%s, %s", g.Namespace, label)
+	}
+	if g.Tenant != "" {
+		label = fmt.Sprintf("tenant:%s, %s", g.Tenant, label)
+	}
+	return label
+}
+
+// formatShare renders the share of g in the total spans of each period.
+func formatShare(g, total GroupCounts) string {
+	return fmt.Sprintf(" | Share(Hourly:%s, Daily:%s, Monthly:%s)",
+		formatPercent(g.Hourly.Total, total.Hourly.Total),
+		formatPercent(g.Daily.Total, total.Daily.Total),
+		formatPercent(g.Monthly.Total, total.Monthly.Total),
+	)
+}
+
+// formatCountsLine renders the counters of a group after its label. Spans-only reports keep
+// the span_report.txt format; with logs or metrics, each period also lists
+// the log records and metric data points, so one line covers every signal.
+func formatCountsLine(displayTime, label string, g GroupCounts, logs, metrics bool) string {
+	line := fmt.Sprintf("[%s] %s", displayTime, label)
+	for _, p := range allPeriods {
+		c := g.counts(p)
+		line += fmt.Sprintf(" | %s(Total:%d, HTTP:%d, SQL:%d", periodLabels[p], c.Total, c.HTTP, c.SQL)
+		if logs {
+			line += fmt.Sprintf(", Logs:%d, LogErrors:%d", c.Logs, c.LogErrors)
+		}
+		if metrics {
+			line += fmt.Sprintf(", DataPoints:%d, Series:%d", c.DataPoints, c.Series)
+		}
+		line += ")"
+	}
+	if metrics {
+		line += fmt.Sprintf(" | HourlyDataPoints(Gauge:%d, Sum:%d, Histogram:%d, ExpHistogram:%d, Summary:%d)",
+			g.Hourly.Gauge, g.Hourly.Sum, g.Hourly.Histogram, g.Hourly.ExponentialHistogram, g.Hourly.Summary,
+		)
+	}
+	return line
+}
+
+// periodLabels are the names of the periods in text reports.
+var periodLabels = [...]string{periodHourly: "Hourly", periodDaily: "Daily", periodMonthly: "Monthly"}
+
+// jsonlRecord is one line of the jsonl sink: a group with the time of the report.
+type jsonlRecord struct {
+	Time time.Time `json:"time"`
+	GroupCounts
+}
+
+// formatJSONLines renders one JSON object per group.
+func formatJSONLines(r Report) ([]string, error) {
+	var lines []string
+	for _, g := range r.Groups {
+		data, err := json.Marshal(jsonlRecord{Time: r.Time, GroupCounts: g})
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, string(data)+"\n")
+	}
+	return lines, nil
+}
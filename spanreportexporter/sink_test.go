@@ -0,0 +1,116 @@
+package spanreportexporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestRotateAndWrite_SinksFailIndependently(t *testing.T) {
+	// 1. A webhook that always fails, between a file sink and a jsonl sink
+	var received []Report
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report Report
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		received = append(received, report)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer webhook.Close()
+
+	dir := t.TempDir()
+	sinks, err := openSinks([]SinkConfig{
+		{Name: "text", Type: sinkTypeFile, Path: filepath.Join(dir, "report.txt")},
+		{Name: "hook", Type: sinkTypeWebhook, URL: webhook.URL, Headers: map[string]string{"X-Token": "secret"}},
+		{Name: "archive", Type: sinkTypeJSONL, Path: filepath.Join(dir, "report.jsonl")},
+	})
+	require.NoError(t, err)
+	exp := &spanReportExporter{
+		sinks:  sinks,
+		logger: componenttest.NewNopTelemetrySettings().Logger,
+		stopCh: make(chan struct{}),
+	}
+	val, _ := exp.statsMap.LoadOrStore(groupingKey{service: "svc-a", env: "prod"}, &spanStats{})
+	val.(*spanStats).hourly.Add(3)
+
+	// 2. Execution
+	exp.rotateAndWrite(time.Date(2025, 12, 18, 10, 0, 0, 0, time.Local))
+
+	// 3. Validation: the failure is counted, the other sinks are written anyway
+	assert.Equal(t, uint64(1), exp.writeErrors.Load())
+	require.Len(t, received, 1)
+	assert.Equal(t, "svc-a", received[0].Groups[0].Service)
+
+	text, err := os.ReadFile(filepath.Join(dir, "report.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(text), "[2025-12-18 09:59:59] service:svc-a, env:prod | Hourly(Total:3,")
+
+	data, err := os.ReadFile(filepath.Join(dir, "report.jsonl"))
+	require.NoError(t, err)
+	var record jsonlRecord
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, "prod", record.Env)
+	assert.Equal(t, uint64(3), record.Hourly.Total)
+}
+
+func TestConfig_Sinks(t *testing.T) {
+	// Without sinks, the top-level options are used
+	cfg := createDefaultConfig().(*Config)
+	cfg.SQLitePath = "report.db"
+	require.NoError(t, cfg.Validate())
+	var types []string
+	for _, s := range cfg.sinkConfigs() {
+		types = append(types, s.Type)
+	}
+	assert.Equal(t, []string{sinkTypeFile, sinkTypeSQLite}, types)
+
+	cfg.Sinks = []SinkConfig{{Type: sinkTypeWebhook}}
+	assert.ErrorContains(t, cfg.Validate(), "url is required")
+	cfg.Sinks = []SinkConfig{{Type: "kafka"}}
+	assert.ErrorContains(t, cfg.Validate(), "unknown sink type")
+	cfg.Sinks = []SinkConfig{{Type: sinkTypeFile, Path: "a.txt"}, {Type: sinkTypeFile, Path: "b.txt"}}
+	assert.ErrorContains(t, cfg.Validate(), "duplicate sink name")
+	cfg.Sinks = []SinkConfig{{Type: sinkTypeFile, Path: "a.txt", MaxPendingLines: -1}}
+	assert.ErrorContains(t, cfg.Validate(), "max_pending_lines")
+	cfg.Sinks = []SinkConfig{{Type: sinkTypeStdout}}
+	assert.ErrorContains(t, cfg.Validate(), "tui")
+	cfg.TUI = false
+	assert.NoError(t, cfg.Validate())
+	cfg.MaxPendingLines = -1
+	assert.ErrorContains(t, cfg.Validate(), "max_pending_lines")
+}
+
+func TestFormatTextLines_AllSignalsPerPeriod(t *testing.T) {
+	g := GroupCounts{
+		Service: "order-api",
+		Env:     "prod",
+		Hourly:  PeriodCounts{Total: 30, HTTP: 20, SQL: 5, Logs: 12, LogErrors: 2, DataPoints: 40, Gauge: 40, Series: 4},
+		Daily:   PeriodCounts{Total: 300, HTTP: 200, SQL: 50, Logs: 120, LogErrors: 3, DataPoints: 400, Gauge: 400, Series: 5},
+	}
+	total, _ := summarize([]GroupCounts{g}, false)
+	report := Report{Time: time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC), Groups: []GroupCounts{g}, Total: total}
+
+	// Spans only: the span_report.txt format is unchanged
+	report.Signals = []string{signalTraces}
+	lines, err := formatTextLines(report)
+	require.NoError(t, err)
+	assert.Contains(t, lines[0], "| Hourly(Total:30, HTTP:20, SQL:5) | Daily(Total:300, HTTP:200, SQL:50) | Monthly(Total:0, HTTP:0, SQL:0) |")
+
+	// All signals: a single line, each period listing every signal
+	report.Signals = []string{signalTraces, signalLogs, signalMetrics}
+	lines, err = formatTextLines(report)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "| Hourly(Total:30, HTTP:20, SQL:5, Logs:12, LogErrors:2, DataPoints:40, Series:4) |")
+	assert.Contains(t, lines[0], "| Daily(Total:300, HTTP:200, SQL:50, Logs:120, LogErrors:3, DataPoints:400, Series:5) |")
+	assert.Contains(t, lines[0], "| HourlyDataPoints(Gauge:40, Sum:0, Histogram:0, ExpHistogram:0, Summary:0) |")
+	assert.Contains(t, lines[1], "service:*, env:* | Hourly(Total:30, HTTP:20, SQL:5, Logs:12, LogErrors:2, DataPoints:40, Series:4)")
+}
@@ -0,0 +1,235 @@
+package spanreportexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	_ "modernc.org/sqlite"
+)
+
+// Schema migrations, applied in order. The index of the last applied
+// migration + 1 is stored in "PRAGMA user_version", so new migrations
+// must only ever be appended to this list.
+var sqliteMigrations = []string{
+	`CREATE TABLE periods (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		reported_at TEXT NOT NULL
+	)`,
+	`CREATE TABLE groups (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		service TEXT NOT NULL,
+		env     TEXT NOT NULL,
+		UNIQUE (service, env)
+	)`,
+	`CREATE TABLE counts (
+		period_id     INTEGER NOT NULL REFERENCES periods(id),
+		group_id      INTEGER NOT NULL REFERENCES groups(id),
+		hourly_total  INTEGER NOT NULL,
+		hourly_http   INTEGER NOT NULL,
+		hourly_sql    INTEGER NOT NULL,
+		daily_total   INTEGER NOT NULL,
+		daily_http    INTEGER NOT NULL,
+		daily_sql     INTEGER NOT NULL,
+		monthly_total INTEGER NOT NULL,
+		monthly_http  INTEGER NOT NULL,
+		monthly_sql   INTEGER NOT NULL,
+		PRIMARY KEY (period_id, group_id)
+	)`,
+	`CREATE INDEX periods_reported_at ON periods (reported_at)`,
+	`ALTER TABLE counts ADD COLUMN hourly_logs INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE counts ADD COLUMN hourly_log_errors INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE counts ADD COLUMN daily_logs INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE counts ADD COLUMN daily_log_errors INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE counts ADD COLUMN monthly_logs INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE counts ADD COLUMN monthly_log_errors INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE metric_counts (
+		period_id             INTEGER NOT NULL REFERENCES periods(id),
+		group_id              INTEGER NOT NULL REFERENCES groups(id),
+		period                TEXT NOT NULL,
+		data_points           INTEGER NOT NULL,
+		gauge                 INTEGER NOT NULL,
+		sum                   INTEGER NOT NULL,
+		histogram             INTEGER NOT NULL,
+		exponential_histogram INTEGER NOT NULL,
+		summary               INTEGER NOT NULL,
+		series                INTEGER NOT NULL,
+		PRIMARY KEY (period_id, group_id, period)
+	)`,
+	// Groups are unique per namespace, which needs a new table in SQLite
+	`CREATE TABLE groups_new (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		namespace TEXT NOT NULL DEFAULT '',
+		service   TEXT NOT NULL,
+		env       TEXT NOT NULL,
+		UNIQUE (namespace, service, env)
+	);
+	INSERT INTO groups_new (id, service, env) SELECT id, service, env FROM groups;
+	DROP TABLE groups;
+	ALTER TABLE groups_new RENAME TO groups`,
+	// Same for tenants
+	`CREATE TABLE groups_new (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant    TEXT NOT NULL DEFAULT '',
+		namespace TEXT NOT NULL DEFAULT '',
+		service   TEXT NOT NULL,
+		env       TEXT NOT NULL,
+		UNIQUE (tenant, namespace, service, env)
+	);
+	INSERT INTO groups_new (id, namespace, service, env) SELECT id, namespace, service, env FROM groups;
+	DROP TABLE groups;
+	ALTER TABLE groups_new RENAME TO groups`,
+	`CREATE TABLE span_kind_counts (
+		period_id INTEGER NOT NULL REFERENCES periods(id),
+		group_id  INTEGER NOT NULL REFERENCES groups(id),
+		period    TEXT NOT NULL,
+		internal  INTEGER NOT NULL,
+		server    INTEGER NOT NULL,
+		client    INTEGER NOT NULL,
+		producer  INTEGER NOT NULL,
+		consumer  INTEGER NOT NULL,
+		PRIMARY KEY (period_id, group_id, period)
+	)`,
+}
+
+// sqliteStore writes every report tick into a local SQLite database,
+// so that the history can be inspected with ad-hoc SQL.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows only one writer at a time
+	db.SetMaxOpenConns(1)
+
+	s := &sqliteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sqliteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(sqliteMigrations))
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		// PRAGMA does not accept placeholders
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write stores all groups of one report in a single transaction.
+func (s *sqliteStore) Write(ctx context.Context, r Report) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	res, err := tx.Exec("INSERT INTO periods (reported_at) VALUES (?)",
+		r.Time.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return err
+	}
+	periodID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	// Span kind and metric counts are only stored when traces and metrics are received
+	traces := slices.Contains(r.Signals, signalTraces)
+	metrics := slices.Contains(r.Signals, signalMetrics)
+	for _, g := range r.Groups {
+		if _, err = tx.Exec(`INSERT INTO groups (tenant, namespace, service, env) VALUES (?, ?, ?, ?)
+			ON CONFLICT (tenant, namespace, service, env) DO NOTHING`,
+			g.Tenant, g.Namespace, g.Service, g.Env); err != nil {
+			return err
+		}
+		var groupID int64
+		if err = tx.QueryRow("SELECT id FROM groups WHERE tenant = ? AND namespace = ? AND service = ? AND env = ?",
+			g.Tenant, g.Namespace, g.Service, g.Env).Scan(&groupID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`INSERT INTO counts (period_id, group_id,
+			hourly_total, hourly_http, hourly_sql,
+			daily_total, daily_http, daily_sql,
+			monthly_total, monthly_http, monthly_sql,
+			hourly_logs, hourly_log_errors,
+			daily_logs, daily_log_errors,
+			monthly_logs, monthly_log_errors)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			periodID, groupID,
+			g.Hourly.Total, g.Hourly.HTTP, g.Hourly.SQL,
+			g.Daily.Total, g.Daily.HTTP, g.Daily.SQL,
+			g.Monthly.Total, g.Monthly.HTTP, g.Monthly.SQL,
+			g.Hourly.Logs, g.Hourly.LogErrors,
+			g.Daily.Logs, g.Daily.LogErrors,
+			g.Monthly.Logs, g.Monthly.LogErrors,
+		); err != nil {
+			return err
+		}
+		if traces {
+			for _, p := range allPeriods {
+				c := g.counts(p)
+				if _, err = tx.Exec(`INSERT INTO span_kind_counts (period_id, group_id, period,
+					internal, server, client, producer, consumer)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+					periodID, groupID, p.String(),
+					c.Internal, c.Server, c.Client, c.Producer, c.Consumer,
+				); err != nil {
+					return err
+				}
+			}
+		}
+		if !metrics {
+			continue
+		}
+		for _, p := range allPeriods {
+			c := g.counts(p)
+			if _, err = tx.Exec(`INSERT INTO metric_counts (period_id, group_id, period,
+				data_points, gauge, sum, histogram, exponential_histogram, summary, series)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				periodID, groupID, p.String(),
+				c.DataPoints, c.Gauge, c.Sum, c.Histogram, c.ExponentialHistogram, c.Summary, c.Series,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,118 @@
+package spanreportexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore_WriteReport(t *testing.T) {
+	// 1. Open a fresh database (migrations are applied on open)
+	path := filepath.Join(t.TempDir(), "report.db")
+	store, err := openSQLiteStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// 2. Write two ticks
+	rows := []GroupCounts{
+		{Service: "svc-a", Env: "prod", Hourly: PeriodCounts{Total: 10, HTTP: 4, SQL: 2, Logs: 7, LogErrors: 1}, Daily: PeriodCounts{Total: 100, HTTP: 40, SQL: 20}, Monthly: PeriodCounts{Total: 1000, HTTP: 400, SQL: 200}},
+		{Namespace: "shop", Service: "svc-a", Env: "prod", Hourly: PeriodCounts{Total: 3}},
+		{Service: "svc-b", Env: "dev", Hourly: PeriodCounts{Total: 1, HTTP: 0, SQL: 0}, Daily: PeriodCounts{Total: 1, HTTP: 0, SQL: 0}, Monthly: PeriodCounts{Total: 1, HTTP: 0, SQL: 0}},
+	}
+	ctx := context.Background()
+	require.NoError(t, store.Write(ctx, Report{Time: time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC), Groups: rows}))
+	require.NoError(t, store.Write(ctx, Report{Time: time.Date(2025, 12, 18, 10, 59, 59, 0, time.UTC), Groups: rows[:1]}))
+
+	// 3. Validation: groups are shared between ticks, counts are stored per tick
+	var periods, groups, counts int
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM periods").Scan(&periods))
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM groups").Scan(&groups))
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM counts").Scan(&counts))
+	assert.Equal(t, 2, periods)
+	assert.Equal(t, 3, groups, "the same service and env in another namespace is another group")
+	assert.Equal(t, 4, counts)
+
+	var reportedAt string
+	var hourlyHTTP, monthlyTotal, hourlyLogs uint64
+	require.NoError(t, store.db.QueryRow(`SELECT p.reported_at, c.hourly_http, c.monthly_total, c.hourly_logs
+		FROM counts c JOIN periods p ON p.id = c.period_id JOIN groups g ON g.id = c.group_id
+		WHERE g.namespace = '' AND g.service = 'svc-a' ORDER BY p.reported_at DESC LIMIT 1`).Scan(&reportedAt, &hourlyHTTP, &monthlyTotal, &hourlyLogs))
+	assert.Equal(t, "2025-12-18 10:59:59", reportedAt)
+	assert.Equal(t, uint64(4), hourlyHTTP)
+	assert.Equal(t, uint64(1000), monthlyTotal)
+	assert.Equal(t, uint64(7), hourlyLogs)
+
+	// 4. Reopening an existing database must not re-run migrations
+	store.Close()
+	reopened, err := openSQLiteStore(path)
+	require.NoError(t, err)
+	reopened.Close()
+}
+
+func TestSQLiteStore_WritesSpanKinds(t *testing.T) {
+	store, err := openSQLiteStore(filepath.Join(t.TempDir(), "report.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	rows := []GroupCounts{{Service: "svc-a", Env: "prod",
+		Hourly: PeriodCounts{Total: 6, Internal: 3, Server: 1, Client: 2},
+		Daily:  PeriodCounts{Total: 60, Internal: 30, Server: 10, Client: 15, Producer: 3, Consumer: 2}}}
+	require.NoError(t, store.Write(ctx, Report{Time: time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC), Signals: []string{signalTraces}, Groups: rows}))
+	// Without traces, there are no span kinds to store
+	require.NoError(t, store.Write(ctx, Report{Time: time.Date(2025, 12, 18, 10, 59, 59, 0, time.UTC), Signals: []string{signalLogs}, Groups: rows}))
+
+	var count int
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM span_kind_counts").Scan(&count))
+	assert.Equal(t, len(allPeriods), count)
+	var internal, server, client, producer, consumer uint64
+	require.NoError(t, store.db.QueryRow(`SELECT internal, server, client, producer, consumer
+		FROM span_kind_counts WHERE period = 'daily'`).Scan(&internal, &server, &client, &producer, &consumer))
+	assert.Equal(t, []uint64{30, 10, 15, 3, 2}, []uint64{internal, server, client, producer, consumer})
+}
+
+func TestSQLiteStore_MigratesGroupsToNamespacesAndTenants(t *testing.T) {
+	// A database written before groups had a namespace and a tenant
+	path := filepath.Join(t.TempDir(), "report.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	before := slices.IndexFunc(sqliteMigrations, func(m string) bool { return strings.Contains(m, "namespace TEXT") })
+	for _, m := range sqliteMigrations[:before] {
+		_, err := db.Exec(m)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d", before))
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO groups (id, service, env) VALUES (7, 'svc-a', 'prod')")
+	require.NoError(t, err)
+	db.Close()
+
+	store, err := openSQLiteStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Write(context.Background(), Report{
+		Time: time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC),
+		Groups: []GroupCounts{
+			{Service: "svc-a", Env: "prod"},
+			{Namespace: "shop", Service: "svc-a", Env: "prod"},
+			{Tenant: "acme", Service: "svc-a", Env: "prod"},
+		},
+	}))
+
+	// The existing group keeps its id and counts, the others are new
+	var groupID int64
+	require.NoError(t, store.db.QueryRow(`SELECT c.group_id FROM counts c JOIN groups g ON g.id = c.group_id
+		WHERE g.tenant = '' AND g.namespace = ''`).Scan(&groupID))
+	assert.Equal(t, int64(7), groupID)
+	var groups int
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM groups").Scan(&groups))
+	assert.Equal(t, 3, groups)
+}
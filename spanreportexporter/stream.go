@@ -0,0 +1,159 @@
+package spanreportexporter
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// streamBufferSize is the number of events a /stream client may fall behind
+// before it is disconnected.
+const streamBufferSize = 8
+
+// streamEvent is pushed to the /stream clients every second.
+type streamEvent struct {
+	Time   time.Time     `json:"time"`
+	Groups []GroupCounts `json:"groups"`
+	Deltas []groupDelta  `json:"deltas"`
+}
+
+// groupDelta is the number of spans a group received since the previous event.
+type groupDelta struct {
+	Tenant    string       `json:"tenant,omitempty"`
+	Namespace string       `json:"namespace,omitempty"`
+	Service   string       `json:"service"`
+	Env       string       `json:"env"`
+	Delta     PeriodCounts `json:"delta"`
+}
+
+// streamHub fans out one encoded event per second to all /stream clients.
+// Events are sent without blocking, so a slow client never delays the others
+// (or ConsumeTraces, which only touches the atomic counters).
+type streamHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	last    map[groupingKey]PeriodCounts
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{
+		clients: make(map[chan []byte]struct{}),
+	}
+}
+
+func (h *streamHub) subscribe() chan []byte {
+	ch := make(chan []byte, streamBufferSize)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *streamHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[ch]; ok {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+// broadcast sends data to every client. A client whose buffer is full is
+// dropped; its channel is closed so that the handler ends the response
+// and the browser reconnects.
+func (h *streamHub) broadcast(data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- data:
+		default:
+			delete(h.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *streamHub) hasClients() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients) > 0
+}
+
+// nextEvent builds the event for the current snapshot, with the deltas
+// relative to the previous call.
+func (h *streamHub) nextEvent(now time.Time, rows []GroupCounts) streamEvent {
+	ev := streamEvent{Time: now, Groups: rows, Deltas: []groupDelta{}}
+	current := make(map[groupingKey]PeriodCounts, len(rows))
+	for _, r := range rows {
+		k := r.key()
+		current[k] = r.Hourly
+		prev, ok := h.last[k]
+		if h.last != nil && !ok {
+			// A new group: everything it has counted is new
+			prev = PeriodCounts{}
+		} else if !ok {
+			// First event: there is nothing to compare with
+			continue
+		}
+		d := PeriodCounts{
+			Total: counterDelta(r.Hourly.Total, prev.Total),
+			HTTP:  counterDelta(r.Hourly.HTTP, prev.HTTP),
+			SQL:   counterDelta(r.Hourly.SQL, prev.SQL),
+
+			Internal: counterDelta(r.Hourly.Internal, prev.Internal),
+			Server:   counterDelta(r.Hourly.Server, prev.Server),
+			Client:   counterDelta(r.Hourly.Client, prev.Client),
+			Producer: counterDelta(r.Hourly.Producer, prev.Producer),
+			Consumer: counterDelta(r.Hourly.Consumer, prev.Consumer),
+
+			Logs:      counterDelta(r.Hourly.Logs, prev.Logs),
+			LogErrors: counterDelta(r.Hourly.LogErrors, prev.LogErrors),
+
+			DataPoints: counterDelta(r.Hourly.DataPoints, prev.DataPoints),
+		}
+		if d != (PeriodCounts{}) {
+			ev.Deltas = append(ev.Deltas, groupDelta{Tenant: r.Tenant, Namespace: r.Namespace, Service: r.Service, Env: r.Env, Delta: d})
+		}
+	}
+	h.last = current
+	return ev
+}
+
+// counterDelta returns the increase of an hourly counter. If the counter has
+// been reset in the meantime, everything counted since the reset is new.
+func counterDelta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// startStreaming pushes an event to the /stream clients every second, the same
+// cadence the TUI uses, until the exporter is stopped.
+func (e *spanReportExporter) startStreaming() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				if !e.stream.hasClients() {
+					// Deltas restart from scratch when somebody connects again
+					e.stream.last = nil
+					continue
+				}
+				data, err := json.Marshal(e.stream.nextEvent(now, e.snapshot()))
+				if err != nil {
+					e.logger.Error("Failed to encode stream event", zap.Error(err))
+					continue
+				}
+				e.stream.broadcast(data)
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,74 @@
+package spanreportexporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// templateFuncs are the helper functions available in report templates.
+var templateFuncs = template.FuncMap{
+	// humanize formats a counter like the TUI, e.g. 12.3k
+	"humanize": func(v uint64) string {
+		return humanize(int64(v))
+	},
+	// percent formats part/total with one decimal place, e.g. 12.5%
+	"percent": formatPercent,
+	// change formats a period-over-period change, e.g. +120 (+8.0%)
+	"change": formatChange,
+	// pad left-aligns v in a column of the given width
+	"pad": func(width int, v any) string {
+		s := fmt.Sprint(v)
+		return s + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s)))
+	},
+	// padLeft right-aligns v in a column of the given width
+	"padLeft": func(width int, v any) string {
+		s := fmt.Sprint(v)
+		return strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s))) + s
+	},
+}
+
+// parseReportTemplate parses a text/template that renders a whole Report,
+// e.g. `{{range .Groups}}{{.Service | pad 20}} {{humanize .Hourly.Total}}{{"\n"}}{{end}}`.
+// The template is executed once with a sample report, so that misspelled
+// fields are reported at config load time rather than at the first report.
+func parseReportTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("report").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	pct := 0.0
+	c := &Change{Percent: &pct}
+	sample := Report{Time: time.Now(), Groups: []GroupCounts{{
+		Service: "sample",
+		Env:     "sample",
+		Changes: PeriodChanges{LastHour: c, HourYesterday: c, DayLastWeek: c, LastMonth: c},
+	}}, Senders: &SenderPeriods{}}
+	sample.Total, sample.EnvTotals = summarize(sample.Groups, true)
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// templateFormatter returns a formatter that renders each report with tmpl.
+// The output is written as a single chunk, terminated by a newline.
+func templateFormatter(tmpl *template.Template) func(Report) ([]string, error) {
+	return func(r Report) ([]string, error) {
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, r); err != nil {
+			return nil, err
+		}
+		out := buf.String()
+		if out == "" {
+			return nil, nil
+		}
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		return []string{out}, nil
+	}
+}
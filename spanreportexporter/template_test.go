@@ -0,0 +1,41 @@
+package spanreportexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportTemplate(t *testing.T) {
+	tmpl, err := parseReportTemplate(`{{.Time.Format "15:04"}}
+{{range .Groups}}{{pad 8 .Service}}|{{padLeft 6 (humanize .Hourly.Total)}}|{{percent .Hourly.HTTP .Hourly.Total}}
+{{end}}`)
+	require.NoError(t, err)
+
+	lines, err := templateFormatter(tmpl)(Report{
+		Time: time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC),
+		Groups: []GroupCounts{
+			{Service: "svc-a", Hourly: PeriodCounts{Total: 12345, HTTP: 1000}},
+			{Service: "svc-b", Hourly: PeriodCounts{}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"09:59\nsvc-a   | 12.3k|8.1%\nsvc-b   |     0|0.0%\n"}, lines)
+}
+
+func TestReportTemplate_ValidatedAtLoad(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Template = `{{range .Groups}}{{.Servce}}{{end}}`
+	assert.ErrorContains(t, cfg.Validate(), "invalid template")
+	cfg.Template = `{{range .Groups}}`
+	assert.ErrorContains(t, cfg.Validate(), "invalid template")
+
+	cfg.Template = `{{range .Senders.Hourly}}{{.Sender}}{{end}}`
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Template = ""
+	cfg.Sinks = []SinkConfig{{Type: sinkTypeJSONL, Path: "r.jsonl", Template: "{{.Time}}"}}
+	assert.ErrorContains(t, cfg.Validate(), "only supported by file and stdout")
+}
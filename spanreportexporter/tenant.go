@@ -0,0 +1,71 @@
+package spanreportexporter
+
+import (
+	"context"
+	"sort"
+
+	"go.opentelemetry.io/collector/client"
+)
+
+// tenantFromContext returns the tenant sent in the given metadata of the
+// request, or "unknown". The receiver must have include_metadata enabled.
+func tenantFromContext(ctx context.Context, header string) string {
+	if values := client.FromContext(ctx).Metadata.Get(header); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return "unknown"
+}
+
+// reserveTenant counts a new group of a tenant, and returns the tenant to
+// count it under: __overflow__ once max_tenants other tenants have groups.
+// The group must be given back with releaseTenant if it is not created.
+func (e *spanReportExporter) reserveTenant(tenant string) string {
+	e.tenantsMu.Lock()
+	defer e.tenantsMu.Unlock()
+	if e.tenantGroups == nil {
+		e.tenantGroups = map[string]int{}
+	}
+	if _, ok := e.tenantGroups[tenant]; !ok && tenant != overflowService {
+		n := len(e.tenantGroups)
+		if _, ok := e.tenantGroups[overflowService]; ok {
+			n--
+		}
+		if n >= e.maxTenants {
+			tenant = overflowService
+		}
+	}
+	e.tenantGroups[tenant]++
+	return tenant
+}
+
+// releaseTenant forgets a group of a tenant, and the tenant with its last group.
+func (e *spanReportExporter) releaseTenant(tenant string) {
+	e.tenantsMu.Lock()
+	defer e.tenantsMu.Unlock()
+	if e.tenantGroups[tenant]--; e.tenantGroups[tenant] <= 0 {
+		delete(e.tenantGroups, tenant)
+	}
+}
+
+// totalsByTenant returns the sum of the groups of each tenant as service "*"
+// and env "*", sorted by tenant. Without tenants, it returns nil.
+func totalsByTenant(groups []GroupCounts) []GroupCounts {
+	index := map[string]int{}
+	var totals []GroupCounts
+	for _, g := range groups {
+		if g.Tenant == "" {
+			continue
+		}
+		i, ok := index[g.Tenant]
+		if !ok {
+			i = len(totals)
+			index[g.Tenant] = i
+			totals = append(totals, GroupCounts{Tenant: g.Tenant, Service: totalKey, Env: totalKey})
+		}
+		totals[i].add(g)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Tenant < totals[j].Tenant
+	})
+	return totals
+}
@@ -0,0 +1,172 @@
+package spanreportexporter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func tenantContext(headers map[string][]string) context.Context {
+	return client.NewContext(context.Background(), client.Info{Metadata: client.NewMetadata(headers)})
+}
+
+func tenantTraces(services ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	for _, service := range services {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", service)
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	}
+	return td
+}
+
+func TestConsumeTraces_CountsPerTenant(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), tenantHeader: "X-Tenant-ID"}
+
+	require.NoError(t, exp.ConsumeTraces(tenantContext(map[string][]string{"X-Tenant-ID": {"acme"}}), tenantTraces("api", "api")))
+	require.NoError(t, exp.ConsumeTraces(tenantContext(map[string][]string{"X-Tenant-ID": {"globex"}}), tenantTraces("api")))
+	// A request without the header is counted under the "unknown" tenant
+	require.NoError(t, exp.ConsumeTraces(context.Background(), tenantTraces("api")))
+
+	report := exp.collectReport(time.Now())
+	counts := map[string]uint64{}
+	for _, g := range report.Groups {
+		counts[g.Tenant+":"+g.Service] = g.Hourly.Total
+	}
+	assert.Equal(t, map[string]uint64{"acme:api": 2, "globex:api": 1, "unknown:api": 1}, counts)
+
+	require.Len(t, report.TenantTotals, 3)
+	assert.Equal(t, "acme", report.TenantTotals[0].Tenant)
+	assert.Equal(t, uint64(2), report.TenantTotals[0].Hourly.Total)
+	assert.Equal(t, "unknown", report.TenantTotals[2].Tenant)
+	assert.Equal(t, uint64(4), report.Total.Hourly.Total)
+}
+
+func TestCollectReport_GroupsSortedByTenant(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), tenantHeader: "X-Tenant-ID"}
+	for _, tenant := range []string{"globex", "acme", "globex", "acme"} {
+		ctx := tenantContext(map[string][]string{"X-Tenant-ID": {tenant}})
+		require.NoError(t, exp.ConsumeTraces(ctx, tenantTraces("web", "api", "db")))
+	}
+
+	report := exp.collectReport(time.Now())
+	var order []string
+	for _, g := range report.Groups {
+		order = append(order, g.Tenant+":"+g.Service)
+	}
+	assert.Equal(t, []string{"acme:api", "acme:db", "acme:web", "globex:api", "globex:db", "globex:web"}, order)
+}
+
+func TestConsumeTraces_MaxGroupsPerTenant(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), tenantHeader: "X-Tenant-ID", maxGroups: 1}
+
+	// A noisy tenant does not take the groups of the others
+	noisy := tenantContext(map[string][]string{"X-Tenant-ID": {"noisy"}})
+	require.NoError(t, exp.ConsumeTraces(noisy, tenantTraces("a", "b", "c")))
+	quiet := tenantContext(map[string][]string{"X-Tenant-ID": {"quiet"}})
+	require.NoError(t, exp.ConsumeTraces(quiet, tenantTraces("a")))
+
+	counts := map[groupingKey]uint64{}
+	for _, entry := range exp.getSortedEntries() {
+		counts[entry.key] = entry.stats.load(periodHourly).Total
+	}
+	assert.Equal(t, map[groupingKey]uint64{
+		{tenant: "noisy", service: "a", env: "unknown"}: 1,
+		exp.overflowKey("noisy"):                        2,
+		{tenant: "quiet", service: "a", env: "unknown"}: 1,
+	}, counts)
+}
+
+func TestConsumeTraces_IgnoresMetadataWithoutTenantHeader(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop()}
+
+	require.NoError(t, exp.ConsumeTraces(tenantContext(map[string][]string{"X-Tenant-ID": {"acme"}}), tenantTraces("api")))
+
+	report := exp.collectReport(time.Now())
+	require.Len(t, report.Groups, 1)
+	assert.Empty(t, report.Groups[0].Tenant)
+	assert.Empty(t, report.TenantTotals)
+}
+
+func TestConsumeTraces_MaxTenants(t *testing.T) {
+	exp := &spanReportExporter{logger: zap.NewNop(), tenantHeader: "X-Tenant-ID", maxTenants: 2}
+	send := func(tenant string) {
+		require.NoError(t, exp.ConsumeTraces(tenantContext(map[string][]string{"X-Tenant-ID": {tenant}}), tenantTraces("api")))
+	}
+
+	// A client making up tenants cannot create more than max_tenants of them
+	for _, tenant := range []string{"acme", "globex", "random-1", "random-2", "acme"} {
+		send(tenant)
+	}
+	counts := map[groupingKey]uint64{}
+	for _, entry := range exp.getSortedEntries() {
+		counts[entry.key] = entry.stats.load(periodHourly).Total
+	}
+	assert.Equal(t, map[groupingKey]uint64{
+		{tenant: "acme", service: "api", env: "unknown"}:          2,
+		{tenant: "globex", service: "api", env: "unknown"}:        1,
+		{tenant: overflowService, service: "api", env: "unknown"}: 2,
+	}, counts)
+
+	// Removing the groups of a tenant makes room for a new one
+	require.NoError(t, exp.resetGroup(groupingKey{tenant: "globex", service: "api", env: "unknown"}, nil))
+	send("initech")
+	_, ok := exp.statsMap.Load(groupingKey{tenant: "initech", service: "api", env: "unknown"})
+	assert.True(t, ok)
+	send("random-3")
+	val, _ := exp.statsMap.Load(groupingKey{tenant: overflowService, service: "api", env: "unknown"})
+	assert.Equal(t, uint64(3), val.(*spanStats).load(periodHourly).Total)
+}
+
+func TestCollectReport_HierarchyPerTenant(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	exp := &spanReportExporter{logger: zap.New(core), tenantHeader: "X-Tenant-ID", hierarchy: true}
+
+	// Two tenants both have a "shop/api" service
+	for tenant, spans := range map[string]int{"acme": 2, "globex": 3} {
+		td := ptrace.NewTraces()
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.namespace", "shop")
+		rs.Resource().Attributes().PutStr("service.name", "api")
+		for range spans {
+			rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		}
+		require.NoError(t, exp.ConsumeTraces(tenantContext(map[string][]string{"X-Tenant-ID": {tenant}}), td))
+	}
+
+	report := exp.collectReport(time.Now())
+	var subtotals []string
+	for _, s := range report.Subtotals {
+		subtotals = append(subtotals, fmt.Sprintf("%s:%s/%s=%d", s.Tenant, s.Namespace, s.Service, s.Hourly.Total))
+	}
+	assert.Equal(t, []string{"acme:shop/*=2", "acme:shop/api=2", "globex:shop/*=3", "globex:shop/api=3"}, subtotals)
+
+	// The TUI tree has a namespace node per tenant, each with its own groups
+	m := model{snapshot: snapshotResponse{Groups: exp.snapshot()}, collapsed: map[string]bool{}}
+	m.snapshot.Subtotals = subtotalsByNamespace(m.snapshot.Groups)
+	m.toggle() // acme:shop
+	var paths []string
+	for _, r := range m.treeRows() {
+		paths = append(paths, r.path)
+	}
+	assert.Equal(t, []string{"acme:shop", "globex:shop", "globex:shop/api", ""}, paths)
+	assert.Equal(t, "▸ acme:shop", m.treeRows()[0].label(true).Service)
+
+	// The snapshot log tells the groups of the tenants apart
+	exp.logSnapshot()
+	var tenants []any
+	for _, entry := range logs.FilterMessage("Snapshot group").All() {
+		fields := entry.ContextMap()
+		assert.Equal(t, "shop", fields["namespace"])
+		tenants = append(tenants, fields["tenant"])
+	}
+	assert.ElementsMatch(t, []any{"acme", "globex"}, tenants)
+}
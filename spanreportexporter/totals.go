@@ -0,0 +1,108 @@
+package spanreportexporter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// totalKey is the service (and env) of the rows that sum up several groups.
+const totalKey = "*"
+
+func (c *PeriodCounts) add(o PeriodCounts) {
+	c.Total += o.Total
+	c.HTTP += o.HTTP
+	c.SQL += o.SQL
+	c.Internal += o.Internal
+	c.Server += o.Server
+	c.Client += o.Client
+	c.Producer += o.Producer
+	c.Consumer += o.Consumer
+	c.Logs += o.Logs
+	c.LogErrors += o.LogErrors
+	c.DataPoints += o.DataPoints
+	c.Gauge += o.Gauge
+	c.Sum += o.Sum
+	c.Histogram += o.Histogram
+	c.ExponentialHistogram += o.ExponentialHistogram
+	c.Summary += o.Summary
+	// Groups differ by resource, so they never share a series
+	c.Series += o.Series
+}
+
+func (g *GroupCounts) add(o GroupCounts) {
+	g.Hourly.add(o.Hourly)
+	g.Daily.add(o.Daily)
+	g.Monthly.add(o.Monthly)
+}
+
+// summarize returns the sum of all groups as service "*" and env "*", and with
+// byEnv, the sum of each environment as service "*", sorted by environment.
+func summarize(groups []GroupCounts, byEnv bool) (GroupCounts, []GroupCounts) {
+	total := GroupCounts{Service: totalKey, Env: totalKey}
+	for _, g := range groups {
+		total.add(g)
+	}
+	if !byEnv {
+		return total, nil
+	}
+
+	index := map[string]int{}
+	envTotals := []GroupCounts{}
+	for _, g := range groups {
+		i, ok := index[g.Env]
+		if !ok {
+			i = len(envTotals)
+			index[g.Env] = i
+			envTotals = append(envTotals, GroupCounts{Service: totalKey, Env: g.Env})
+		}
+		envTotals[i].add(g)
+	}
+	sort.Slice(envTotals, func(i, j int) bool {
+		return envTotals[i].Env < envTotals[j].Env
+	})
+	return total, envTotals
+}
+
+// formatPercent formats part/total with one decimal place, e.g. 12.5%.
+func formatPercent(part, total uint64) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+}
+
+// subtotalsByNamespace returns the subtotals of each level of the hierarchy:
+// for each namespace, its sum as service "*" and env "*", followed by the sum
+// of each of its services as env "*". Namespaces of different tenants are
+// summed separately. Without namespaces, it returns nil.
+func subtotalsByNamespace(groups []GroupCounts) []GroupCounts {
+	index := map[groupingKey]int{}
+	var subtotals []GroupCounts
+	addTo := func(k groupingKey, g GroupCounts) {
+		i, ok := index[k]
+		if !ok {
+			i = len(subtotals)
+			index[k] = i
+			subtotals = append(subtotals, GroupCounts{Tenant: k.tenant, Namespace: k.namespace, Service: k.service, Env: k.env})
+		}
+		subtotals[i].add(g)
+	}
+	for _, g := range groups {
+		if g.Namespace == "" {
+			continue
+		}
+		addTo(groupingKey{tenant: g.Tenant, namespace: g.Namespace, service: totalKey, env: totalKey}, g)
+		addTo(groupingKey{tenant: g.Tenant, namespace: g.Namespace, service: g.Service, env: totalKey}, g)
+	}
+	// The namespace row sorts before its services, since "*" is before letters
+	sort.Slice(subtotals, func(i, j int) bool {
+		if subtotals[i].Tenant != subtotals[j].Tenant {
+			return subtotals[i].Tenant < subtotals[j].Tenant
+		}
+		if subtotals[i].Namespace != subtotals[j].Namespace {
+			return subtotals[i].Namespace < subtotals[j].Namespace
+		}
+		return subtotals[i].Service < subtotals[j].Service
+	})
+	return subtotals
+}
@@ -0,0 +1,40 @@
+package spanreportexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_TotalsAndShares(t *testing.T) {
+	groups := []GroupCounts{
+		{Service: "order-api", Env: "prod", Hourly: PeriodCounts{Total: 30, HTTP: 20, SQL: 5}, Daily: PeriodCounts{Total: 300, HTTP: 200, SQL: 50}},
+		{Service: "auth-svc", Env: "prod", Hourly: PeriodCounts{Total: 10, HTTP: 0, SQL: 0}, Daily: PeriodCounts{Total: 100, HTTP: 0, SQL: 0}},
+		{Service: "order-api", Env: "dev", Hourly: PeriodCounts{Total: 10, HTTP: 10, SQL: 0}, Daily: PeriodCounts{Total: 100, HTTP: 100, SQL: 0}},
+	}
+
+	// Without subtotals, only the grand total is computed
+	total, envTotals := summarize(groups, false)
+	assert.Equal(t, GroupCounts{Service: "*", Env: "*", Hourly: PeriodCounts{Total: 50, HTTP: 30, SQL: 5}, Daily: PeriodCounts{Total: 500, HTTP: 300, SQL: 50}}, total)
+	assert.Nil(t, envTotals)
+
+	total, envTotals = summarize(groups, true)
+	require.Len(t, envTotals, 2)
+	assert.Equal(t, "dev", envTotals[0].Env)
+	assert.Equal(t, PeriodCounts{Total: 40, HTTP: 20, SQL: 5}, envTotals[1].Hourly)
+
+	lines, err := formatTextLines(Report{
+		Time:      time.Date(2025, 12, 18, 9, 59, 59, 0, time.UTC),
+		Groups:    groups,
+		Total:     total,
+		EnvTotals: envTotals,
+	})
+	require.NoError(t, err)
+	require.Len(t, lines, 6)
+	assert.Contains(t, lines[0], "service:order-api, env:prod | Hourly(Total:30,")
+	assert.Contains(t, lines[0], "| Share(Hourly:60.0%, Daily:60.0%, Monthly:0.0%) |")
+	assert.Contains(t, lines[3], "service:*, env:dev | Hourly(Total:10,")
+	assert.Contains(t, lines[5], "service:*, env:* | Hourly(Total:50, HTTP:30, SQL:5)")
+}
@@ -2,15 +2,25 @@ package spanreportexporter
 
 import (
 	"fmt"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+// anomalyStyle highlights the groups flagged by the anomaly detection
+var anomalyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+
+// selectedStyle marks the tree row under the cursor
+var selectedStyle = lipgloss.NewStyle().Reverse(true)
+
+// overflowStyle highlights the __overflow__ group, see max_groups
+var overflowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
+
 // Message to refresh the screen every second
 type tickMsg time.Time
 
@@ -20,16 +30,42 @@ func tick() tea.Cmd {
 	})
 }
 
+// snapshotFunc returns the counters to display, either read from the local
+// exporter or fetched from a running collector by the attach command.
+type snapshotFunc func() (snapshotResponse, error)
+
 type model struct {
-	exporter  *spanReportExporter
-	table     table.Model
-	startTime time.Time
+	source   snapshotFunc
+	table    table.Model
+	snapshot snapshotResponse
+	err      error
+	// teams shows the roll-ups by team instead of the groups, see ownership
+	teams bool
+	// senders shows the top senders instead of the groups, see senderTracker
+	senders bool
+	// kinds adds the columns of the spans by kind
+	kinds bool
+	// collapsed holds the tree nodes that are collapsed, and cursor the selected row, with hierarchy
+	collapsed map[string]bool
+	cursor    int
 }
 
 func NewTUIModel(e *spanReportExporter) model {
-	return model{
-		exporter:  e,
-		startTime: time.Now(),
+	return newTUIModel(e.localSnapshot)
+}
+
+func newTUIModel(source snapshotFunc) model {
+	m := model{source: source, collapsed: map[string]bool{}}
+	m.refresh()
+	return m
+}
+
+// refresh fetches the latest counters. On failure, the last snapshot is kept on screen.
+func (m *model) refresh() {
+	snap, err := m.source()
+	m.err = err
+	if err == nil {
+		m.snapshot = snap
 	}
 }
 
@@ -43,8 +79,27 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "t":
+			m.teams = !m.teams
+			return m, nil
+		case "s":
+			m.senders = !m.senders
+			return m, nil
+		case "K":
+			m.kinds = !m.kinds
+			return m, nil
+		case "up", "k":
+			m.moveCursor(-1)
+			return m, nil
+		case "down", "j":
+			m.moveCursor(1)
+			return m, nil
+		case "enter", " ":
+			m.toggle()
+			return m, nil
 		}
 	case tickMsg:
+		m.refresh()
 		m.table.SetRows(m.generateRows())
 		return m, tick()
 	}
@@ -56,42 +111,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m model) generateRows() []table.Row {
 	var rows []table.Row
 
-	type entry struct {
-		key   groupingKey
-		stats *spanStats
-	}
-
-	var entries []entry
-
-	m.exporter.statsMap.Range(func(key, value interface{}) bool {
-		entries = append(entries, entry{
-			key:   key.(groupingKey),
-			stats: value.(*spanStats),
-		})
-		return true
-	})
-
-	sort.Slice(entries, func(i, j int) bool {
-		if entries[i].key.service != entries[j].key.service {
-			return entries[i].key.service < entries[j].key.service
-		}
-		return entries[i].key.env < entries[j].key.env
-	})
-
-	for _, e := range entries {
-		s := e.stats
+	// Groups are already sorted by service name -> environment name
+	for _, g := range m.snapshot.Groups {
 		rows = append(rows, table.Row{
-			e.key.service,
-			e.key.env,
+			g.Service,
+			g.Env,
 			// Hourly: Total / HTTP / SQL
-			fmt.Sprintf("%d / %d / %d",
-				s.hourly.Load(), s.httpHourly.Load(), s.sqlHourly.Load()),
+			fmt.Sprintf("%d / %d / %d", g.Hourly.Total, g.Hourly.HTTP, g.Hourly.SQL),
 			// Daily: Total / HTTP / SQL
-			fmt.Sprintf("%d / %d / %d",
-				s.daily.Load(), s.httpDaily.Load(), s.sqlDaily.Load()),
+			fmt.Sprintf("%d / %d / %d", g.Daily.Total, g.Daily.HTTP, g.Daily.SQL),
 			// Monthly: Total / HTTP / SQL
-			fmt.Sprintf("%d / %d / %d",
-				s.monthly.Load(), s.httpMonthly.Load(), s.sqlMonthly.Load()),
+			fmt.Sprintf("%d / %d / %d", g.Monthly.Total, g.Monthly.HTTP, g.Monthly.SQL),
 		})
 	}
 	return rows
@@ -120,41 +150,221 @@ func (m model) View() string {
 	var b strings.Builder
 
 	// Header information
-	uptime := time.Since(m.startTime).Round(time.Second)
-	b.WriteString(fmt.Sprintf(" [Span Report Monitor]  Time: %s | Uptime: %s\n",
+	uptime := time.Since(m.snapshot.StartTime).Round(time.Second)
+	b.WriteString(fmt.Sprintf(" [Span Report Monitor]  Time: %s | Uptime: %s",
 		time.Now().Format("15:04:05"), uptime))
-	b.WriteString(" Legend: T=Total, H=HTTP, S=SQL\n\n")
+	if m.snapshot.WriteErrors > 0 {
+		b.WriteString(fmt.Sprintf(" | Write errors: %d (pending lines: %d)",
+			m.snapshot.WriteErrors, m.snapshot.PendingLines))
+	}
+	if m.err != nil {
+		b.WriteString(fmt.Sprintf(" | Error: %v", m.err))
+	}
+	b.WriteString("\n")
+	logs := slices.Contains(m.snapshot.Signals, signalLogs)
+	metrics := slices.Contains(m.snapshot.Signals, signalMetrics)
+	legend := " Legend: T=Total, H=HTTP, S=SQL"
+	if logs {
+		legend += ", LOGS/ERR+=log records (all / ERROR or higher)"
+	}
+	if metrics {
+		legend += ", DPS/SERIES=metric data points / distinct series (approx.)"
+	}
+	if m.kinds {
+		legend += ", INT/SRV/CLI/PRD/CNS=spans by kind"
+	}
+	b.WriteString(legend + "\n\n")
+
+	if m.senders && m.snapshot.Senders != nil {
+		b.WriteString(m.sendersView())
+		b.WriteString("\n (Press 's' to go back to the groups, 'q' or 'Ctrl+C' to exit)")
+		return b.String()
+	}
 
 	// Header row (with clear separators)
-	// Total width is about 85 characters, fitting within a typical terminal width of 80-100 characters.
-	header := fmt.Sprintf("%-12s %-7s | %-17s | %-17s | %-18s\n",
-		"SERVICE", "ENV", "  HOURLY (T/H/S)", "  DAILY (T/H/S)", "  MONTHLY (T/H/S)")
-	b.WriteString(header)
-	b.WriteString(strings.Repeat("-", 12) + "-" + strings.Repeat("-", 8) + "+" +
-		strings.Repeat("-", 19) + "+" + strings.Repeat("-", 19) + "+" +
-		strings.Repeat("-", 20) + "\n")
-
-	// Render data
-	entries := m.exporter.getSortedEntries() // Sorted entries
-	for _, e := range entries {
-		s := e.stats
-
-		// Function to format a group of three numbers for one period
-		fmtGroup := func(t, h, s uint64) string {
-			return fmt.Sprintf("%5s %5s %5s", humanize(int64(t)), humanize(int64(h)), humanize(int64(s)))
+	// Total width is about 100 characters, plus 40 for each of logs and metrics, and 100 for the span kinds.
+	teams := m.teams && len(m.snapshot.Teams) > 0
+	nameHeader, envHeader := "SERVICE", "ENV"
+	if teams {
+		nameHeader, envHeader = "TEAM", "COST CTR"
+	}
+	header := fmt.Sprintf("%-12s %-7s | %-23s | %-23s | %-23s",
+		nameHeader, envHeader, "  HOURLY (T/H/S/%)", "  DAILY (T/H/S/%)", "  MONTHLY (T/H/S/%)")
+	separator := strings.Repeat("-", 12) + "-" + strings.Repeat("-", 8) + "+" +
+		strings.Repeat("-", 25) + "+" + strings.Repeat("-", 25) + "+" +
+		strings.Repeat("-", 25)
+	if logs {
+		header += fmt.Sprintf(" | %-17s | %-17s", "  LOGS (H/D/M)", "  ERR+ (H/D/M)")
+		separator += "+" + strings.Repeat("-", 19) + "+" + strings.Repeat("-", 18)
+	}
+	if metrics {
+		header += fmt.Sprintf(" | %-17s | %-17s", "  DPS (H/D/M)", "  SERIES (H/D/M)")
+		separator += "+" + strings.Repeat("-", 19) + "+" + strings.Repeat("-", 18)
+	}
+	if m.kinds {
+		for _, name := range []string{"INT", "SRV", "CLI", "PRD", "CNS"} {
+			header += fmt.Sprintf(" | %-17s", "  "+name+" (H/D/M)")
+			separator += "+" + strings.Repeat("-", 19)
 		}
+	}
+	b.WriteString(header + "\n")
+	separator += "\n"
+	b.WriteString(separator)
 
-		line := fmt.Sprintf("%-12s %-7s | %s | %s | %s\n",
-			truncate(e.key.service, 12),
-			truncate(e.key.env, 7),
-			fmtGroup(s.hourly.Load(), s.httpHourly.Load(), s.sqlHourly.Load()),
-			fmtGroup(s.daily.Load(), s.httpDaily.Load(), s.sqlDaily.Load()),
-			fmtGroup(s.monthly.Load(), s.httpMonthly.Load(), s.sqlMonthly.Load()),
+	// Function to format a group of three numbers for one period, with the share of the total
+	total := m.snapshot.Total
+	fmtGroup := func(c, t PeriodCounts) string {
+		return fmt.Sprintf("%5s %5s %5s %6s", humanize(int64(c.Total)), humanize(int64(c.HTTP)), humanize(int64(c.SQL)),
+			formatPercent(c.Total, t.Total))
+	}
+	fmtRow := func(g GroupCounts) string {
+		name := g.Service
+		if g.Tenant != "" {
+			name = g.Tenant + ":" + name
+		}
+		row := fmt.Sprintf("%-12s %-7s | %s | %s | %s",
+			truncate(name, 12),
+			truncate(g.Env, 7),
+			fmtGroup(g.Hourly, total.Hourly),
+			fmtGroup(g.Daily, total.Daily),
+			fmtGroup(g.Monthly, total.Monthly),
 		)
-		b.WriteString(line)
+		if logs {
+			row += fmt.Sprintf(" | %5s %5s %5s | %5s %5s %5s",
+				humanize(int64(g.Hourly.Logs)), humanize(int64(g.Daily.Logs)), humanize(int64(g.Monthly.Logs)),
+				humanize(int64(g.Hourly.LogErrors)), humanize(int64(g.Daily.LogErrors)), humanize(int64(g.Monthly.LogErrors)),
+			)
+		}
+		if metrics {
+			row += fmt.Sprintf(" | %5s %5s %5s | %5s %5s %5s",
+				humanize(int64(g.Hourly.DataPoints)), humanize(int64(g.Daily.DataPoints)), humanize(int64(g.Monthly.DataPoints)),
+				humanize(int64(g.Hourly.Series)), humanize(int64(g.Daily.Series)), humanize(int64(g.Monthly.Series)),
+			)
+		}
+		if m.kinds {
+			for _, kind := range []func(PeriodCounts) uint64{
+				func(c PeriodCounts) uint64 { return c.Internal },
+				func(c PeriodCounts) uint64 { return c.Server },
+				func(c PeriodCounts) uint64 { return c.Client },
+				func(c PeriodCounts) uint64 { return c.Producer },
+				func(c PeriodCounts) uint64 { return c.Consumer },
+			} {
+				row += fmt.Sprintf(" | %5s %5s %5s",
+					humanize(int64(kind(g.Hourly))), humanize(int64(kind(g.Daily))), humanize(int64(kind(g.Monthly))))
+			}
+		}
+		return row + "\n"
+	}
+
+	// Render data; groups whose last hour was unusual are highlighted, as well as the overflow group
+	anomalies := 0
+	overflow := false
+	if teams {
+		for _, t := range m.snapshot.Teams {
+			b.WriteString(fmtRow(t.counts()))
+		}
+	} else if m.treeMode() {
+		for i, r := range m.treeRows() {
+			row := strings.TrimSuffix(fmtRow(r.label(m.collapsed[r.path])), "\n")
+			var style *lipgloss.Style
+			switch {
+			case r.g.isOverflow():
+				overflow = true
+				style = &overflowStyle
+			case r.g.Anomaly != nil:
+				anomalies++
+				style = &anomalyStyle
+			}
+			if i == m.cursor {
+				style = &selectedStyle
+			}
+			if style != nil {
+				row = style.Render(row)
+			}
+			b.WriteString(row + "\n")
+		}
+	} else {
+		for _, g := range m.snapshot.Groups {
+			if g.isOverflow() {
+				overflow = true
+				b.WriteString(overflowStyle.Render(strings.TrimSuffix(fmtRow(g), "\n")) + "\n")
+				continue
+			}
+			if g.Anomaly == nil {
+				b.WriteString(fmtRow(g))
+				continue
+			}
+			anomalies++
+			b.WriteString(anomalyStyle.Render(strings.TrimSuffix(fmtRow(g), "\n")) + "\n")
+		}
+	}
+
+	// Tenant totals, environment subtotals and the grand total
+	if len(m.snapshot.Groups) > 0 {
+		b.WriteString(separator)
+		for _, g := range m.snapshot.TenantTotals {
+			b.WriteString(fmtRow(g))
+		}
+		for _, g := range m.snapshot.EnvTotals {
+			b.WriteString(fmtRow(g))
+		}
+		total.Service, total.Env = "TOTAL", ""
+		b.WriteString(fmtRow(total))
 	}
 
-	b.WriteString("\n (Press 'q' or 'Ctrl+C' to exit)")
+	if anomalies > 0 {
+		b.WriteString(anomalyStyle.Render(fmt.Sprintf("\n %d group(s) deviated from their usual volume in the last hour", anomalies)))
+		b.WriteString("\n")
+	}
+
+	if overflow {
+		b.WriteString(overflowStyle.Render(fmt.Sprintf("\n Too many groups: new ones are counted under %s (see max_groups and max_tenants)", overflowService)))
+		b.WriteString("\n")
+	}
+
+	keys := []string{}
+	if m.treeMode() {
+		keys = append(keys, "Up/Down to select, Enter to collapse or expand")
+	}
+	if len(m.snapshot.Teams) > 0 {
+		keys = append(keys, "'t' to switch between services and teams")
+	}
+	if m.snapshot.Senders != nil {
+		keys = append(keys, "'s' to show the top senders")
+	}
+	keys = append(keys, "'K' to toggle the span kind columns")
+	keys = append(keys, "'q' or 'Ctrl+C' to exit")
+	b.WriteString("\n (Press " + strings.Join(keys, ", ") + ")")
+	return b.String()
+}
+
+// sendersView renders the top senders of each period side by side, by number
+// of spans, log records and data points. Counts prefixed with "~" may include
+// data sent before the sender was tracked.
+func (m model) sendersView() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-4s | %-28s | %-28s | %-28s\n", "#", "  HOURLY (sender/items)", "  DAILY (sender/items)", "  MONTHLY (sender/items)"))
+	b.WriteString(strings.Repeat("-", 5) + "+" + strings.Repeat("-", 30) + "+" + strings.Repeat("-", 30) + "+" + strings.Repeat("-", 30) + "\n")
+	s := m.snapshot.Senders
+	rows := max(len(s.Hourly), len(s.Daily), len(s.Monthly))
+	if rows == 0 {
+		b.WriteString(" No data received yet\n")
+	}
+	for i := range rows {
+		row := fmt.Sprintf("%-4d", i+1)
+		for _, p := range allPeriods {
+			name, items := "", ""
+			if senders := *s.senders(p); i < len(senders) {
+				c := senders[i]
+				name, items = truncate(c.Sender, 20), humanize(int64(c.items()+c.Error))
+				if c.Error > 0 {
+					items = "~" + items
+				}
+			}
+			row += fmt.Sprintf(" | %-20s %7s", name, items)
+		}
+		b.WriteString(row + "\n")
+	}
 	return b.String()
 }
 
@@ -0,0 +1,87 @@
+package spanreportexporter
+
+import "strings"
+
+// treeRow is one visible line of the namespace → service → env tree, with
+// the namespaces of each tenant as separate nodes.
+type treeRow struct {
+	// path identifies a namespace ("shop", or "acme:shop" with a tenant) or a
+	// service ("shop/api") node; empty for groups
+	path  string
+	depth int
+	g     GroupCounts
+}
+
+// treeMode reports whether the groups are shown as a tree, with hierarchy.
+func (m model) treeMode() bool {
+	return !m.teams && len(m.snapshot.Subtotals) > 0
+}
+
+// treeRows returns the visible lines of the tree: the subtotal of each
+// namespace and service, and the groups of the services that are expanded.
+func (m model) treeRows() []treeRow {
+	groups := map[groupingKey][]GroupCounts{}
+	for _, g := range m.snapshot.Groups {
+		k := groupingKey{tenant: g.Tenant, namespace: g.Namespace, service: g.Service}
+		groups[k] = append(groups[k], g)
+	}
+
+	var rows []treeRow
+	for _, s := range m.snapshot.Subtotals {
+		namespace := s.Namespace
+		if s.Tenant != "" {
+			namespace = s.Tenant + ":" + namespace
+		}
+		if s.Service == totalKey {
+			rows = append(rows, treeRow{path: namespace, depth: 0, g: s})
+			continue
+		}
+		if m.collapsed[namespace] {
+			continue
+		}
+		path := namespace + "/" + s.Service
+		rows = append(rows, treeRow{path: path, depth: 1, g: s})
+		if m.collapsed[path] {
+			continue
+		}
+		for _, g := range groups[groupingKey{tenant: s.Tenant, namespace: s.Namespace, service: s.Service}] {
+			rows = append(rows, treeRow{depth: 2, g: g})
+		}
+	}
+	return rows
+}
+
+// label returns the row with the tree drawn in the service column. The tenant
+// is shown on the namespace node only.
+func (r treeRow) label(collapsed bool) GroupCounts {
+	g := r.g
+	marker := "▾ "
+	if collapsed {
+		marker = "▸ "
+	}
+	switch r.depth {
+	case 0:
+		g.Service, g.Env = marker+r.path, ""
+	case 1:
+		g.Service, g.Env = "  "+marker+g.Service, ""
+	default:
+		g.Service = strings.Repeat(" ", 4)
+	}
+	g.Tenant = ""
+	return g
+}
+
+// toggle collapses or expands the node under the cursor.
+func (m *model) toggle() {
+	rows := m.treeRows()
+	if m.cursor < len(rows) && rows[m.cursor].path != "" {
+		path := rows[m.cursor].path
+		m.collapsed[path] = !m.collapsed[path]
+	}
+}
+
+// moveCursor moves the cursor by delta lines, within the visible rows.
+func (m *model) moveCursor(delta int) {
+	n := len(m.treeRows())
+	m.cursor = max(0, min(m.cursor+delta, n-1))
+}